@@ -0,0 +1,385 @@
+package windigo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// A Bar is a labelled bar of a BarChart, with a value for each series.
+type Bar struct {
+	Label  string
+	Values []float64
+}
+
+// BarMode is how a BarChart draws the values of a bar.
+type BarMode int
+
+const (
+	// Each series is a bar of its own, side by side.
+	Grouped BarMode = iota
+	// The series are piled up in one bar.
+	Stacked
+)
+
+// BarChartType draws bars, growing up from a row of labels if its
+// Orientation is Vertical or right from a column of labels if it is
+// Horizontal, to an eighth of a cell with the Unicode block elements.
+// Each bar has a value for each of Series, drawn in the series' Fg,
+// either side by side or stacked according to Mode.  Bars are BarWidth
+// cells thick, with Gap cells between them, and bars that do not fit are
+// left out.  The bars are scaled from 0, negative values count as 0, to
+// Max, or if Max is 0 to the greatest bar.  If Annotate is set each bar
+// is annotated with its value, or a stack with its total, printed with
+// Format.
+type BarChartType struct {
+	WidgetType
+	Orientation OrientationType
+	Mode        BarMode
+	Series      []Series
+	Max         float64
+	BarWidth    int
+	Gap         int
+	Annotate    bool
+	Format      string
+
+	mu   sync.Mutex
+	bars []Bar
+}
+
+func NewBarChart(r *Region, o OrientationType, series []Series, fg, bg Attribute) (*BarChartType, error) {
+
+	if len(series) == 0 {
+		return nil, errors.New("NewBarChart: no series")
+	}
+
+	b := new(BarChartType)
+	b.init(r, o, series, fg, bg)
+	b.Fsm = NewEventFSM(b.event)
+	return b, nil
+}
+
+func (b *BarChartType) init(r *Region, o OrientationType, series []Series, fg, bg Attribute) {
+	b.X = r.X
+	b.Y = r.Y
+	b.W = r.W
+	b.H = r.H
+	b.Fg = fg
+	b.Bg = bg
+	b.kbd = -1
+	b.Orientation = o
+	b.Series = append([]Series(nil), series...)
+	b.BarWidth = 1
+	if o == Vertical {
+		b.BarWidth = 3
+	}
+	b.Gap = 1
+	b.Format = "%g"
+}
+
+func (b *BarChartType) Init() error {
+	b.Start()
+	return nil
+}
+
+func (b *BarChartType) event(e *Event) *Event {
+	return WidgetResult(Nop)
+}
+
+// SetBars replaces the bars and redraws the chart.
+func (b *BarChartType) SetBars(bars []Bar) {
+	b.mu.Lock()
+	b.bars = make([]Bar, len(bars))
+	for i, bar := range bars {
+		b.bars[i] = Bar{bar.Label, append([]float64(nil), bar.Values...)}
+	}
+	b.mu.Unlock()
+
+	if b.Managed() {
+		QueueRedraw(b)
+	}
+}
+
+// SetBar replaces bar i and redraws the chart.
+func (b *BarChartType) SetBar(i int, bar Bar) error {
+	b.mu.Lock()
+	if i < 0 || i >= len(b.bars) {
+		b.mu.Unlock()
+		return errors.New("SetBar: no such bar")
+	}
+	b.bars[i] = Bar{bar.Label, append([]float64(nil), bar.Values...)}
+	b.mu.Unlock()
+
+	if b.Managed() {
+		QueueRedraw(b)
+	}
+	return nil
+}
+
+// Bars returns the bars.
+func (b *BarChartType) Bars() []Bar {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bars := make([]Bar, len(b.bars))
+	for i, bar := range b.bars {
+		bars[i] = Bar{bar.Label, append([]float64(nil), bar.Values...)}
+	}
+	return bars
+}
+
+func positive(v float64) float64 {
+	if v > 0 {
+		return v
+	}
+	return 0
+}
+
+// scale returns the value of a full length bar.
+func (b *BarChartType) scale(bars []Bar) float64 {
+	if b.Max > 0 {
+		return b.Max
+	}
+	max := 0.0
+	for _, bar := range bars {
+		t := 0.0
+		for _, v := range bar.Values {
+			if b.Mode == Stacked {
+				t += positive(v)
+			} else {
+				t = math.Max(t, positive(v))
+			}
+		}
+		max = math.Max(max, t)
+	}
+	if max == 0 {
+		return 1
+	}
+	return max
+}
+
+// seriesFg returns the color of series i.
+func (b *BarChartType) seriesFg(i int) Attribute {
+	if i < len(b.Series) {
+		return b.Series[i].Fg
+	}
+	fg, _ := b.Colors()
+	return fg
+}
+
+// thickness returns the number of cells across a bar, or a group of
+// bars, and the number of series.
+func (b *BarChartType) thickness(bars []Bar) (int, int) {
+	ns := len(b.Series)
+	for _, bar := range bars {
+		if len(bar.Values) > ns {
+			ns = len(bar.Values)
+		}
+	}
+	bw := b.BarWidth
+	if bw < 1 {
+		bw = 1
+	}
+	if b.Mode == Stacked {
+		return bw, ns
+	}
+	return bw * ns, ns
+}
+
+// barCells returns the cells of a bar n cells long, from its base, made
+// of segments of vals in colors.  A cell where one segment ends and the
+// next begins is drawn with the lower segment's block in its color on
+// the next segment's color.  It also returns the number of cells the
+// bar reaches into.
+func (b *BarChartType) barCells(vals []float64, colors []Attribute, scale float64, n int) ([]Cell, int) {
+	_, bg := b.Colors()
+	cells := make([]Cell, n)
+	for i := range cells {
+		cells[i] = Cell{Ch: ' ', Bg: bg}
+	}
+
+	ends := make([]int, len(vals))
+	t := 0.0
+	for i, v := range vals {
+		t += positive(v)
+		ends[i] = int(math.Round(math.Min(t/scale, 1) * float64(8*n)))
+	}
+	segment := func(e int) int {
+		for i, end := range ends {
+			if e < end {
+				return i
+			}
+		}
+		return -1
+	}
+
+	reach := 0
+	for j := 0; j < n; j++ {
+		s := segment(8 * j)
+		if s < 0 {
+			break
+		}
+		reach = j + 1
+		covered := ends[s] - 8*j
+		if covered >= 8 {
+			cells[j] = Cell{Ch: gaugeFull, Fg: colors[s], Bg: bg}
+			continue
+		}
+		cells[j] = Cell{Ch: gaugeLeft - rune(covered), Fg: colors[s], Bg: bg}
+		if b.Orientation == Vertical {
+			cells[j].Ch = gaugeLower + rune(covered)
+		}
+		if next := segment(8*j + covered); next >= 0 {
+			cells[j].Bg = colors[next]
+		}
+	}
+	return cells, reach
+}
+
+// annotation returns the text annotating vals.
+func (b *BarChartType) annotation(vals []float64) string {
+	t := 0.0
+	for _, v := range vals {
+		t += v
+	}
+	return fmt.Sprintf(b.Format, t)
+}
+
+// segments returns the bars drawn for bar, each with its values, colors
+// and offset across the group, in cells.
+func (b *BarChartType) segments(bar Bar, ns int) ([][]float64, [][]Attribute, []int) {
+	var vals [][]float64
+	var colors [][]Attribute
+	var offs []int
+
+	if b.Mode == Stacked {
+		c := make([]Attribute, len(bar.Values))
+		for i := range c {
+			c[i] = b.seriesFg(i)
+		}
+		return [][]float64{bar.Values}, [][]Attribute{c}, []int{0}
+	}
+	bw := b.BarWidth
+	if bw < 1 {
+		bw = 1
+	}
+	for s := 0; s < ns; s++ {
+		v := 0.0
+		if s < len(bar.Values) {
+			v = bar.Values[s]
+		}
+		vals = append(vals, []float64{v})
+		colors = append(colors, []Attribute{b.seriesFg(s)})
+		offs = append(offs, s*bw)
+	}
+	return vals, colors, offs
+}
+
+func (b *BarChartType) Refresh() {
+
+	if !b.Managed() {
+		return
+	}
+
+	bars := b.Bars()
+
+	fg, bg := b.Colors()
+	w, h := b.Size()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			SetCell(b, x, y, ' ', fg, bg)
+		}
+	}
+
+	scale := b.scale(bars)
+	gw, ns := b.thickness(bars)
+	bw := b.BarWidth
+	if bw < 1 {
+		bw = 1
+	}
+
+	if b.Orientation == Vertical {
+		base := h - 1
+		top := 0
+		if b.Annotate {
+			top = 1
+		}
+		n := base - top
+		if n <= 0 {
+			return
+		}
+		for i, x := 0, 0; i < len(bars) && x+gw <= w; i, x = i+1, x+gw+b.Gap {
+			label := truncate(bars[i].Label, gw, false)
+			drawText(b, x+alignOffset(label, gw, AlignCenter), base, gw, label, fg, bg)
+
+			vals, colors, offs := b.segments(bars[i], ns)
+			for k := range vals {
+				cells, reach := b.barCells(vals[k], colors[k], scale, n)
+				for j, c := range cells {
+					for dx := 0; dx < bw; dx++ {
+						SetCell(b, x+offs[k]+dx, base-1-j, c.Ch, c.Fg, c.Bg)
+					}
+				}
+				if !b.Annotate {
+					continue
+				}
+				s := b.annotation(vals[k])
+				width := gw
+				if b.Mode == Grouped {
+					width = bw
+				}
+				if runewidth.StringWidth(s) <= width {
+					drawText(b, x+offs[k]+alignOffset(s, width, AlignCenter),
+						base-1-reach, width, s, fg, bg)
+				}
+			}
+		}
+		return
+	}
+
+	lw := 0
+	aw := 0
+	for _, bar := range bars {
+		if sw := runewidth.StringWidth(bar.Label); sw > lw {
+			lw = sw
+		}
+		if b.Annotate {
+			vals, _, _ := b.segments(bar, ns)
+			for _, v := range vals {
+				if sw := runewidth.StringWidth(b.annotation(v)) + 1; sw > aw {
+					aw = sw
+				}
+			}
+		}
+	}
+	if lw > 0 {
+		lw++
+	}
+	if lw > w/3 {
+		lw = w / 3
+	}
+	n := w - lw - aw
+	if n <= 0 {
+		return
+	}
+	for i, y := 0, 0; i < len(bars) && y+gw <= h; i, y = i+1, y+gw+b.Gap {
+		drawText(b, 0, y+(gw-1)/2, lw-1, bars[i].Label, fg, bg)
+
+		vals, colors, offs := b.segments(bars[i], ns)
+		for k := range vals {
+			cells, reach := b.barCells(vals[k], colors[k], scale, n)
+			for j, c := range cells {
+				for dy := 0; dy < bw; dy++ {
+					SetCell(b, lw+j, y+offs[k]+dy, c.Ch, c.Fg, c.Bg)
+				}
+			}
+			if b.Annotate {
+				x := lw + reach + 1
+				drawText(b, x, y+offs[k]+(bw-1)/2, w-x, b.annotation(vals[k]), fg, bg)
+			}
+		}
+	}
+}
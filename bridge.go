@@ -0,0 +1,392 @@
+package windigo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// The bridge lets external programs drive and observe a running UI over
+// a local unix domain socket speaking JSON-RPC (net/rpc/jsonrpc).
+// Objects are addressed by the names given to them with SetName.
+// The service is registered as "Windigo", so methods are called as
+// "Windigo.Push", "Windigo.Subscribe", "Windigo.Next",
+// "Windigo.Unsubscribe", "Windigo.List", "Windigo.Diagram" and
+// "Windigo.History".
+//
+// Push sends an event to an Object's Yin, as if its container had sent
+// it.  Subscribe taps the Object's output (the events it sends with
+// PushEvent) and Next long-polls for the next one.
+
+// How long Push waits for the object to accept an event, and the longest
+// Next will wait for an event.
+var BridgeTimeout = 5 * time.Second
+
+// How many undelivered events are kept per subscription.  The oldest
+// events are dropped once a subscriber falls this far behind.
+const bridgeQueueLen = 64
+
+// BridgeEvent is the wire representation of an Event.  EventName, if
+// set, takes precedence over EventType and may name an application
+// defined event type.  Payload is the JSON encoding of the event's typed
+// Payload.
+type BridgeEvent struct {
+	Object    string
+	EventType WindigoEventType
+	EventName string
+	Payload   json.RawMessage
+	Type      PayloadType
+	Rc        RetCode
+	Err       string
+	Val       []int
+	Sval      []string
+}
+
+type BridgeSubscribeArgs struct {
+	Object string
+}
+
+type BridgeNextArgs struct {
+	Id int
+	// Milliseconds to wait, 0 means BridgeTimeout.
+	Timeout int
+}
+
+type BridgeDiagramArgs struct {
+	Object string
+	// "dot" or "mermaid".
+	Format string
+}
+
+// BridgeTransition is the wire representation of a TransitionRecord.
+type BridgeTransition struct {
+	Time    time.Time
+	Src     string
+	Trigger *BridgeEvent
+	Rc      string
+	Dst     string
+	Timed   bool
+}
+
+type Bridge struct {
+	path     string
+	listener net.Listener
+	server   *rpc.Server
+}
+
+// Bridge RPC service.
+type BridgeService struct{}
+
+type subscription struct {
+	id     int
+	object string
+	C      chan *Event
+}
+
+type subscribersType struct {
+	sync.Mutex
+	next int
+	byId map[int]*subscription
+	// Subscriptions keyed by the Object's output channel (comm[0].Yang).
+	byChan map[chan *Event][]*subscription
+}
+
+var subscribers = subscribersType{
+	byId:   make(map[int]*subscription),
+	byChan: make(map[chan *Event][]*subscription),
+}
+
+// ServeBridge listens on the unix socket at path and serves bridge
+// requests until Close is called.  A stale socket file is removed.
+func ServeBridge(path string) (*Bridge, error) {
+
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	b := new(Bridge)
+	b.path = path
+	b.listener = l
+	b.server = rpc.NewServer()
+	err = b.server.RegisterName("Windigo", new(BridgeService))
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	go b.accept()
+
+	return b, nil
+}
+
+func (b *Bridge) accept() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+func (b *Bridge) Close() error {
+	err := b.listener.Close()
+	os.Remove(b.path)
+	return err
+}
+
+// Push sends the event to the named Object's input channel.
+func (s *BridgeService) Push(args *BridgeEvent, reply *int) error {
+
+	o, err := Lookup(args.Object)
+	if err != nil {
+		return err
+	}
+	yin, err := Yin(o)
+	if err != nil {
+		return err
+	}
+
+	et := args.EventType
+	if args.EventName != "" {
+		var ok bool
+		et, ok = EventTypeByName(args.EventName)
+		if !ok {
+			return errors.New("Push: unknown event type " + args.EventName)
+		}
+	}
+
+	e := NewEvent(et)
+	if len(args.Payload) > 0 {
+		t := PayloadOf(et)
+		if t == nil {
+			return errors.New("Push: " + et.String() + " has no payload type")
+		}
+		p := reflect.New(t)
+		err = json.Unmarshal(args.Payload, p.Interface())
+		if err != nil {
+			return err
+		}
+		e.Payload = p.Elem().Interface()
+	}
+	e.Args = new(ArgType)
+	e.Args.Type = args.Type
+	e.Args.Val = args.Val
+	e.Args.Sval = args.Sval
+
+	select {
+	case yin <- e:
+	case <-time.After(BridgeTimeout):
+		return errors.New("Push: " + args.Object + " is not accepting events")
+	}
+	*reply = 0
+	return nil
+}
+
+// Subscribe returns a subscription id for the named Object's output.
+func (s *BridgeService) Subscribe(args *BridgeSubscribeArgs, reply *int) error {
+
+	o, err := Lookup(args.Object)
+	if err != nil {
+		return err
+	}
+	yang, err := Yang(o)
+	if err != nil {
+		return err
+	}
+
+	subscribers.Lock()
+	defer subscribers.Unlock()
+
+	subscribers.next++
+	sub := &subscription{subscribers.next, args.Object,
+		make(chan *Event, bridgeQueueLen)}
+	subscribers.byId[sub.id] = sub
+	subscribers.byChan[yang] = append(subscribers.byChan[yang], sub)
+
+	*reply = sub.id
+	return nil
+}
+
+// Next waits for the next event on a subscription.
+func (s *BridgeService) Next(args *BridgeNextArgs, reply *BridgeEvent) error {
+
+	subscribers.Lock()
+	sub, ok := subscribers.byId[args.Id]
+	subscribers.Unlock()
+	if !ok {
+		return errors.New("Next: no such subscription")
+	}
+
+	timeout := BridgeTimeout
+	if args.Timeout > 0 {
+		timeout = time.Duration(args.Timeout) * time.Millisecond
+	}
+
+	select {
+	case e, ok := <-sub.C:
+		if !ok {
+			return errors.New("Next: subscription closed")
+		}
+		*reply = *bridgeEvent(sub.object, e)
+	case <-time.After(timeout):
+		return errors.New("Next: timeout")
+	}
+	return nil
+}
+
+func (s *BridgeService) Unsubscribe(id *int, reply *int) error {
+
+	subscribers.Lock()
+	defer subscribers.Unlock()
+
+	sub, ok := subscribers.byId[*id]
+	if !ok {
+		return errors.New("Unsubscribe: no such subscription")
+	}
+	delete(subscribers.byId, *id)
+	for c, subs := range subscribers.byChan {
+		for i := range subs {
+			if subs[i] == sub {
+				subs = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(subs) == 0 {
+			delete(subscribers.byChan, c)
+		} else {
+			subscribers.byChan[c] = subs
+		}
+	}
+	close(sub.C)
+	*reply = 0
+	return nil
+}
+
+// unsubscribeAll closes the subscriptions to the output channel c of an
+// Object that has exited.
+func unsubscribeAll(c chan *Event) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+
+	for _, sub := range subscribers.byChan[c] {
+		delete(subscribers.byId, sub.id)
+		close(sub.C)
+	}
+	delete(subscribers.byChan, c)
+}
+
+// List returns the paths of all named Objects.
+func (s *BridgeService) List(args *int, reply *[]string) error {
+	*reply = Objects()
+	return nil
+}
+
+// Diagram returns the named Object's state machine as a DOT or Mermaid
+// diagram with its current state marked.
+func (s *BridgeService) Diagram(args *BridgeDiagramArgs, reply *string) error {
+
+	o, err := Lookup(args.Object)
+	if err != nil {
+		return err
+	}
+	so, ok := o.(Stateful)
+	if !ok || so.FSM() == nil {
+		return errors.New("Diagram: " + args.Object + " has no state machine")
+	}
+
+	var b bytes.Buffer
+	switch args.Format {
+	case "", "dot":
+		err = so.FSM().WriteDOT(&b, args.Object, true)
+	case "mermaid":
+		err = so.FSM().WriteMermaid(&b, true)
+	default:
+		err = errors.New("Diagram: unknown format " + args.Format)
+	}
+	*reply = b.String()
+	return err
+}
+
+// History returns the named Object's state machine transition history,
+// oldest first.
+func (s *BridgeService) History(args *BridgeSubscribeArgs, reply *[]BridgeTransition) error {
+
+	o, err := Lookup(args.Object)
+	if err != nil {
+		return err
+	}
+	so, ok := o.(Stateful)
+	if !ok || so.FSM() == nil {
+		return errors.New("History: " + args.Object + " has no state machine")
+	}
+
+	fsm := so.FSM()
+	for _, r := range fsm.TransitionHistory() {
+		bt := BridgeTransition{Time: r.Time, Src: fsm.Name(r.Src),
+			Rc: r.Rc.String(), Dst: fsm.Name(r.Dst), Timed: r.Timed}
+		if r.Trigger != nil {
+			bt.Trigger = bridgeEvent(args.Object, r.Trigger)
+		}
+		*reply = append(*reply, bt)
+	}
+	return nil
+}
+
+func bridgeEvent(object string, e *Event) *BridgeEvent {
+	be := new(BridgeEvent)
+	be.Object = object
+	be.EventType = e.EventType
+	be.EventName = e.EventType.String()
+	if e.Payload != nil {
+		p, err := json.Marshal(e.Payload)
+		if err == nil {
+			be.Payload = p
+		}
+	}
+	if e.Result != nil {
+		be.Type = e.Result.Type
+		be.Rc = e.Result.Rc
+		if e.Result.Err != nil {
+			be.Err = e.Result.Err.Error()
+		}
+		be.Val = e.Result.Val
+		be.Sval = e.Result.Sval
+	}
+	// Events sent to an Object, rather than by it, carry Args.
+	if e.Args != nil && len(be.Val) == 0 && len(be.Sval) == 0 {
+		be.Type = e.Args.Type
+		be.Val = e.Args.Val
+		be.Sval = e.Args.Sval
+	}
+	return be
+}
+
+// publish hands an Object's output event to any bridge subscribers.
+// It never blocks the publishing Object.
+func publish(c chan *Event, e *Event) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+
+	for _, sub := range subscribers.byChan[c] {
+		select {
+		case sub.C <- e:
+		default:
+			// Drop the oldest event to make room.
+			select {
+			case <-sub.C:
+			default:
+			}
+			sub.C <- e
+		}
+	}
+}
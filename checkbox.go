@@ -0,0 +1,107 @@
+package windigo
+
+import termbox "github.com/nsf/termbox-go"
+
+// The marks drawn before a Checkbox's label.
+var (
+	CheckboxOff = "[ ] "
+	CheckboxOn  = "[x] "
+)
+
+// CheckboxType is a two state toggle built on NewFSM's round-robin
+// machine, with its active states named "unchecked" and "checked".  It
+// toggles when clicked, or on Space or Enter while it has the keyboard
+// focus, and sends WindEventToggle with its new value to its container.
+// Send it WindEventSetState to check or uncheck it.
+type CheckboxType struct {
+	WidgetType
+
+	unchecked, checked FiniteState
+}
+
+func (c *CheckboxType) Map(r *Region, ev *termbox.Event, cs FiniteState) interface{} {
+
+	return nil
+}
+
+func NewCheckbox(r *Region, label string, fg, bg Attribute) (*CheckboxType, error) {
+
+	c := new(CheckboxType)
+	c.X = r.X
+	c.Y = r.Y
+	c.W = r.W
+	c.H = r.H
+	c.Fg = fg
+	c.Bg = bg
+	c.kbd = -1
+	c.allowFocus = true
+
+	c.Fsm = NewFSM(c, *String2Sigil(CheckboxOff+label, fg, bg),
+		*String2Sigil(CheckboxOn+label, fg, bg))
+	c.unchecked, _ = c.Fsm.StateByName("active0")
+	c.checked, _ = c.Fsm.StateByName("active1")
+	c.Fsm.StateName[c.unchecked] = "unchecked"
+	c.Fsm.StateName[c.checked] = "checked"
+	c.Fsm.StateFunc[c.unchecked] = c.toggle(true)
+	c.Fsm.StateFunc[c.checked] = c.toggle(false)
+
+	return c, nil
+}
+
+func (c *CheckboxType) Init() error {
+
+	x, y := c.Loc()
+	w, h := c.Size()
+	p := c.Ancestor()
+
+	r := Region{TopLeft{x, y}, WidthHeight{w, h}, false, false, false}
+	ch, err := RegClickable(p, r)
+	if err != nil {
+		return err
+	}
+	c.InputChan = append(c.InputChan, ch)
+
+	c.Start()
+
+	return nil
+}
+
+// toggle returns the state function of an active state, which moves to
+// the other state (Ok) when the checkbox is activated.
+func (c *CheckboxType) toggle(to bool) WidgetStateFunc {
+	return func(ev *termbox.Event) *Event {
+		if activated(&c.WidgetType, ev) {
+			return NewEvent(WindEventToggle, to)
+		}
+		return WidgetResult(Nop)
+	}
+}
+
+// activated reports whether ev clicks the widget, taking the keyboard
+// focus, or is Space or Enter.  Key events only arrive while the widget
+// has the focus.
+func activated(w *WidgetType, ev *termbox.Event) bool {
+	switch ev.Type {
+	case termbox.EventMouse:
+		if ev.Key == termbox.MouseLeft {
+			w.ReqFocus()
+			return true
+		}
+	case termbox.EventKey:
+		return ev.Key == termbox.KeySpace || ev.Key == termbox.KeyEnter
+	}
+	return false
+}
+
+// Checked reports whether the checkbox is checked.
+func (c *CheckboxType) Checked() bool {
+	return c.Fsm.State() == c.checked
+}
+
+func (c *CheckboxType) Refresh() {
+
+	if !c.Managed() {
+		return
+	}
+	drawGlyph(c, c.Fsm.Glyph())
+}
@@ -0,0 +1,123 @@
+package windigo
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for timed state transitions and
+// animations.  The default is the system clock; tests may substitute a
+// FakeClock with SetClock or per state machine with
+// FiniteStateMachine.Clock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine after d.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+type Timer interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+var defClock Clock = realClock{}
+
+// SetClock replaces the default Clock.  It should be called before any
+// state machine is started.
+func SetClock(c Clock) {
+	defClock = c
+}
+
+// FakeClock is a Clock that only moves when told to.  Timers fire
+// synchronously, in order, from Advance.
+type FakeClock struct {
+	sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	c       *FakeClock
+	when    time.Time
+	f       func()
+	stopped bool
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := new(FakeClock)
+	c.now = now
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.Lock()
+	defer c.Unlock()
+
+	t := &fakeTimer{c: c, when: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer that falls
+// due on the way.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Lock()
+	end := c.now.Add(d)
+	c.Unlock()
+
+	for {
+		c.Lock()
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].when.Before(c.timers[j].when)
+		})
+		if len(c.timers) == 0 || c.timers[0].when.After(end) {
+			c.now = end
+			c.Unlock()
+			return
+		}
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = t.when
+		stopped := t.stopped
+		c.Unlock()
+
+		if !stopped {
+			t.f()
+		}
+	}
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.c.Lock()
+	defer t.c.Unlock()
+
+	if t.stopped {
+		return false
+	}
+	t.stopped = true
+	for i, x := range t.c.timers {
+		if x == t {
+			t.c.timers = append(t.c.timers[:i], t.c.timers[i+1:]...)
+			return true
+		}
+	}
+	// Already fired.
+	return false
+}
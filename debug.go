@@ -0,0 +1,8 @@
+//go:build windigo_debug
+// +build windigo_debug
+
+package windigo
+
+// Debug builds (go build -tags windigo_debug) validate state machines
+// as they are created.
+const debugBuild = true
@@ -1,6 +1,14 @@
 package windigo
 
-import termbox "github.com/nsf/termbox-go"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	termbox "github.com/nsf/termbox-go"
+)
 
 type ResultType struct {
 	Rc   RetCode
@@ -24,6 +32,15 @@ type Event struct {
 	EventType WindigoEventType
 	Args      *ArgType
 	Result    *ResultType
+	// Typed payload of an application defined event type.
+	// See RegisterEventType.
+	Payload interface{}
+	// Request/reply correlation.  A request has a non zero Id and
+	// the reply's ReplyTo is set to it.  A request may be ignored
+	// after its Deadline.  See Request and Respond.
+	Id       uint64
+	ReplyTo  uint64
+	Deadline time.Time
 }
 
 type WindigoEventType int
@@ -37,9 +54,55 @@ const (
 	WindEventOutput
 	WindEventMove
 	WindEventResize
+	// Ask an Object for its current value, see Request.
+	WindEventQuery
+	WindEventReply
+	// A termbox input event, in Args.Tbox.
+	WindEventInput
+	// Move an Object's state machine to the state named by the string
+	// Payload, see RestoreState.
+	WindEventSetState
+
+	// Event types used by the library's widgets, the payload each
+	// expects is noted with it.
+
+	// Replace a widget's text.  string.
+	WindEventSetText
+	// Sent to a widget to give it the keyboard focus (true).  A widget's
+	// state functions receive it when the widget gains or loses focus.
+	// bool.
+	WindEventFocus
+	// A widget's text was changed by the user.  string.
+	WindEventTextChange
+	// The user pressed Enter in a text widget.  string.
+	WindEventSubmit
+	// A Checkbox was toggled, the payload is whether it is now checked.
+	// bool.
+	WindEventToggle
+	// The user activated an item, i.e. clicked it or pressed Enter on
+	// it.  The payload is the item's index.  int.
+	WindEventActivate
+	// The selection of a RadioGroup, Table or single select ListBox
+	// changed.  Selection.
+	WindEventSelectionChange
+	// The selection of a multiple select ListBox changed.  The payload
+	// is the indexes of the selected items in increasing order.  []int.
+	WindEventMultiSelectionChange
+	// Set a numeric input widget's value.  float64.
+	WindEventSetValue
+	// The user changed a numeric input widget's value.  float64.
+	WindEventValueChange
+	// Add a value to the end of a chart's series.  Sample.
+	WindEventAppend
 	nWindigoEvents
 )
 
+var windigoEventNames = [nWindigoEvents]string{"None", "Init", "Exit",
+	"Error", "Restart", "Output", "Move", "Resize", "Query", "Reply",
+	"Input", "SetState", "SetText", "Focus", "TextChange", "Submit",
+	"Toggle", "Activate", "SelectionChange", "MultiSelectionChange",
+	"SetValue", "ValueChange", "Append"}
+
 type PayloadType int
 
 const (
@@ -54,9 +117,186 @@ const (
 	None
 )
 
+// Application defined event types are allocated above the library's
+// own event types by RegisterEventType, so they never collide with
+// event types added to the library later.
+type eventTypeInfo struct {
+	name    string
+	payload reflect.Type
+}
+
+type eventTypesType struct {
+	sync.Mutex
+	next   WindigoEventType
+	info   map[WindigoEventType]eventTypeInfo
+	byName map[string]WindigoEventType
+}
+
+var eventTypes = eventTypesType{
+	next: nWindigoEvents,
+	info: map[WindigoEventType]eventTypeInfo{
+		WindEventSetState:             {"SetState", reflect.TypeOf("")},
+		WindEventSetText:              {"SetText", reflect.TypeOf("")},
+		WindEventFocus:                {"Focus", reflect.TypeOf(false)},
+		WindEventTextChange:           {"TextChange", reflect.TypeOf("")},
+		WindEventSubmit:               {"Submit", reflect.TypeOf("")},
+		WindEventToggle:               {"Toggle", reflect.TypeOf(false)},
+		WindEventActivate:             {"Activate", reflect.TypeOf(0)},
+		WindEventSelectionChange:      {"SelectionChange", reflect.TypeOf(Selection{})},
+		WindEventMultiSelectionChange: {"MultiSelectionChange", reflect.TypeOf([]int(nil))},
+		WindEventSetValue:             {"SetValue", reflect.TypeOf(0.0)},
+		WindEventValueChange:          {"ValueChange", reflect.TypeOf(0.0)},
+		WindEventAppend:               {"Append", reflect.TypeOf(Sample{})},
+	},
+	byName: make(map[string]WindigoEventType),
+}
+
+// RegisterEventType allocates a new, unique WindigoEventType named name.
+// If payload is not nil, events of this type are expected to carry a
+// Payload of the same type as payload (i.e. pass AlarmAck{} to have
+// NewEvent accept AlarmAck payloads.)  Registering a name twice is an
+// error.
+func RegisterEventType(name string, payload interface{}) (WindigoEventType, error) {
+	eventTypes.Lock()
+	defer eventTypes.Unlock()
+
+	if _, ok := eventTypes.byName[name]; ok {
+		err := errors.New("RegisterEventType: " + name + " already registered")
+		return WindEventNone, err
+	}
+	for _, n := range windigoEventNames {
+		if n == name {
+			err := errors.New("RegisterEventType: " + name + " is a windigo event type")
+			return WindEventNone, err
+		}
+	}
+
+	et := eventTypes.next
+	eventTypes.next++
+
+	info := eventTypeInfo{name: name}
+	if payload != nil {
+		info.payload = reflect.TypeOf(payload)
+	}
+	eventTypes.info[et] = info
+	eventTypes.byName[name] = et
+
+	return et, nil
+}
+
+// EventTypeByName returns the event type registered as name.  The
+// library's own event types are named without the WindEvent prefix.
+func EventTypeByName(name string) (WindigoEventType, bool) {
+	for i, n := range windigoEventNames {
+		if n == name {
+			return WindigoEventType(i), true
+		}
+	}
+
+	eventTypes.Lock()
+	defer eventTypes.Unlock()
+
+	et, ok := eventTypes.byName[name]
+	return et, ok
+}
+
+// PayloadOf returns the payload type registered for et, or nil.
+func PayloadOf(et WindigoEventType) reflect.Type {
+	eventTypes.Lock()
+	defer eventTypes.Unlock()
+
+	return eventTypes.info[et].payload
+}
+
+func (et WindigoEventType) String() string {
+	if et >= 0 && et < nWindigoEvents {
+		return windigoEventNames[et]
+	}
+
+	eventTypes.Lock()
+	defer eventTypes.Unlock()
+
+	if info, ok := eventTypes.info[et]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("WindigoEventType(%d)", int(et))
+}
+
+// NewEvent returns a new event of type et.  An optional argument is
+// stored as the event's Payload.  If a payload type was registered for
+// et and the argument isn't of that type, the event's Result is set to
+// Fail with an error.
 func NewEvent(et WindigoEventType, args ...interface{}) *Event {
 	e := new(Event)
 	e.Result = new(ResultType)
 	e.EventType = et
+
+	if len(args) > 0 && args[0] != nil {
+		e.Payload = args[0]
+		t := PayloadOf(et)
+		if t != nil && !reflect.TypeOf(args[0]).AssignableTo(t) {
+			e.Result.Rc = Fail
+			e.Result.Err = fmt.Errorf("NewEvent: %s payload must be %s, not %T",
+				et, t, args[0])
+		}
+	}
+	return e
+}
+
+// Selection is the payload of WindEventSelectionChange.
+type Selection struct {
+	Index int
+	Label string
+}
+
+// Sample is the payload of WindEventAppend.  Series is the index of the
+// series Value is added to; it is ignored by charts of a single series.
+type Sample struct {
+	Series int
+	Value  float64
+}
+
+// InputEvent wraps a termbox input event in a WindEventInput event.
+func InputEvent(ev *termbox.Event) *Event {
+	e := NewEvent(WindEventInput)
+	e.Args = new(ArgType)
+	e.Args.Type = PassThru
+	e.Args.Tbox = ev
 	return e
 }
+
+// An EventHandler is called by a container's EventMgr for each event
+// of the type it was registered for.  from is the child Object that
+// sent the event (nil if it came from the container's parent.)
+type EventHandler func(from Object, e *Event)
+
+// Handler is implemented by containers whose EventMgr dispatches events
+// to registered EventHandlers.
+type Handler interface {
+	Handle(WindigoEventType, EventHandler)
+	Handlers(WindigoEventType) []EventHandler
+}
+
+// handlerTable is embedded in containers to implement Handler.
+type handlerTable struct {
+	sync.Mutex
+	handlers map[WindigoEventType][]EventHandler
+}
+
+// Handle registers h to be called for events of type et.
+func (t *handlerTable) Handle(et WindigoEventType, h EventHandler) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.handlers == nil {
+		t.handlers = make(map[WindigoEventType][]EventHandler)
+	}
+	t.handlers[et] = append(t.handlers[et], h)
+}
+
+func (t *handlerTable) Handlers(et WindigoEventType) []EventHandler {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.handlers[et]
+}
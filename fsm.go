@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 
 	termbox "github.com/nsf/termbox-go"
 )
@@ -17,12 +18,48 @@ const (
 	Nop
 )
 
+var retCodeNames = []string{"Fail", "Ok", "Repeat", "Nop"}
+
+func (rc RetCode) String() string {
+	i := int(rc - Fail)
+	if i >= 0 && i < len(retCodeNames) {
+		return retCodeNames[i]
+	}
+	return fmt.Sprintf("RetCode(%d)", int(rc))
+}
+
+// ParseRetCode returns the RetCode named s, i.e. "Ok".
+func ParseRetCode(s string) (RetCode, error) {
+	for i, n := range retCodeNames {
+		if n == s {
+			return Fail + RetCode(i), nil
+		}
+	}
+	return Fail, errors.New("unknown RetCode " + s)
+}
+
 type FiniteState int
 
 type Transition struct {
 	SrcState FiniteState
 	Rc       RetCode
 	DstState FiniteState
+	// Optional condition on the transition, see AddGuardedTransition.
+	Guard     Guard
+	GuardName string
+}
+
+// A Guard decides whether a transition may be taken.  trigger is the
+// event the state function was run on (for termbox input, a
+// WindEventInput event) and result is what the state function
+// returned.  Either may be nil when the machine is advanced directly
+// with NextState or Advance.  Widget state is available from fsm or
+// from the guard's closure.
+type Guard func(fsm *FiniteStateMachine, trigger, result *Event) bool
+
+// Stateful is implemented by Objects driven by a FiniteStateMachine.
+type Stateful interface {
+	FSM() *FiniteStateMachine
 }
 
 type WidgetStateFunc func(ev *termbox.Event) *Event
@@ -38,12 +75,67 @@ type FiniteStateMachine struct {
 	// This info is separate for each "state", so each state can
 	// represent the widget via different colors and or characters.
 	Sigil []Sigil
+	// Optional looks of more than one row, with index representing
+	// state, drawn in place of the state's Sigil, see SetGlyph.  May be
+	// shorter than Sigil.
+	Glyphs []Glyph
+	// Optional animations, with index representing state, see
+	// SetFrames.  May be shorter than Sigil.
+	Animations []Animation
+	// Called, from the Clock's goroutine, whenever an animated state
+	// shows a new frame.  NewFSM sets it to redraw the widget.
+	OnFrame func()
+	// Called whenever the machine enters a new state, after the OnEnter
+	// actions, which are left to the machine's user.  NewFSM sets it to
+	// redraw the widget.
+	OnChange func()
+	// Called by Stop, when the widget's InputEventMgr returns, for
+	// widgets that run timers of their own.
+	OnStop func()
 	// State functions with index representing state.
 	StateFunc []WidgetStateFunc
+	// Optional windigo event functions, with index representing state.
+	// These are run for events the widget's container sends it on
+	// Comm[0].Yin.  A state with an EventFunc but no StateFunc is also
+	// run for termbox input, wrapped by InputEvent.  May be shorter than
+	// StateFunc.
+	EventFunc []GadgetStateFunc
+	// Optional state names, with index representing state.
+	StateName []string
 	// Table of src, rc, dst transitions, where src and dst are
 	// indices(states) into the statefunc array, and rc is one of the
 	// Finite State Machine return codes(Ok, Fail, Repeat, Nop).
 	Transitions []Transition
+
+	// Hierarchy, see fsmtree.go.  These may be shorter than StateFunc,
+	// missing entries are top level, non composite states with no
+	// actions.
+	//
+	// The composite state containing each state, or -1.
+	Parent []FiniteState
+	// The child a composite state is entered through, or -1.
+	Initial []FiniteState
+	// Composite states that resume their last active child.
+	History []bool
+	// Actions run when a state is entered or exited.
+	OnEnter []StateAction
+	OnExit  []StateAction
+	// The last active child of each composite state.
+	last []FiniteState
+
+	// Timed transitions, see fsmtimer.go.
+	Timeouts []TimedTransition
+	// Clock for timed transitions.  nil means the default Clock.
+	Clock  Clock
+	timing *fsmTimers
+
+	// Transition history and observers, see fsmhistory.go.
+	history *fsmHistory
+	// Animation of the current state, see sigil.go.
+	anim *fsmFrames
+	// Guards CurrentState for readers on other goroutines (State())
+	// and the lazily created tables above.
+	mu sync.Mutex
 }
 
 // NewFSM returns a State (the entry state for the new state machine),
@@ -65,7 +157,9 @@ type FiniteStateMachine struct {
 // finite state machine's tables.  This allows for a more complex
 // state machine (than the one provided here) to be used. State functions
 // are: func (*termbbox.Event) *Event.  WidgetResult should be used
-// for return values.
+// for return values.  States that should also run on windigo events
+// sent by the widget's container are given an EventFunc with
+// SetEventFunc, or are added with AddEventState.
 func NewFSM(w Widget, activeStates ...Sigil) *FiniteStateMachine {
 
 	var entryState, exitState, activeState, firstActiveState FiniteState
@@ -87,14 +181,16 @@ func NewFSM(w Widget, activeStates ...Sigil) *FiniteStateMachine {
 
 	if n > 0 {
 		glyph := activeStates[0]
-		entryState = fsm.AddState(entry, glyph)
-		exitState = fsm.AddState(exit, glyph)
+		entryState = fsm.AddNamedState("entry", entry, glyph)
+		exitState = fsm.AddNamedState("exit", exit, glyph)
 		// Add first active state.
-		firstActiveState = fsm.AddState(active, glyph)
+		firstActiveState = fsm.AddNamedState("active0", active, glyph)
 		activeState = firstActiveState
 		// Add entry state transitions.
 		fsm.AddTransition(entryState, Ok, activeState)
 		fsm.AddTransition(entryState, Fail, exitState)
+		fsm.AddTransition(entryState, Repeat, entryState)
+		fsm.AddTransition(entryState, Nop, entryState)
 		// No transitions are necessary for the exit state.
 
 		// Add Transition table entries for 1st active state.
@@ -107,7 +203,8 @@ func NewFSM(w Widget, activeStates ...Sigil) *FiniteStateMachine {
 		// Add additional states creating round-robin transition table
 		// entries.
 		for s := FiniteState(1); s < FiniteState(n); s++ {
-			as := fsm.AddState(active, activeStates[s])
+			name := fmt.Sprintf("active%d", int(s))
+			as := fsm.AddNamedState(name, active, activeStates[s])
 			// Add transition for previous state.
 			fsm.AddTransition(activeState, Ok, as)
 			// Add transition entries for this state.
@@ -124,6 +221,52 @@ func NewFSM(w Widget, activeStates ...Sigil) *FiniteStateMachine {
 	fsm.EntryState = entryState
 	fsm.ExitState = exitState
 	fsm.CurrentState = entryState
+
+	// Redraw the widget's Sigil whenever it enters a new state.
+	if o, ok := w.(Object); ok && n > 0 {
+		fsm.OnChange = func() { QueueRedraw(o) }
+		fsm.OnFrame = func() { QueueRedraw(o) }
+	}
+
+	if debugBuild && n > 0 {
+		err := fsm.Validate()
+		if err != nil {
+			panic(err)
+		}
+	}
+	return fsm
+}
+
+// NewEventFSM returns a state machine with a single active state whose
+// function, f, is run for every input event and windigo event the widget
+// receives.  It suits widgets that keep their state in their own model
+// rather than in the machine, i.e. a Label or a TextInput.  f returns
+// WidgetResult(Nop) to send nothing to the container, or an event with
+// Rc Ok to send it.
+func NewEventFSM(f GadgetStateFunc) *FiniteStateMachine {
+
+	fsm := new(FiniteStateMachine)
+
+	entry := func(ev *termbox.Event) *Event { return WidgetResult(Nop, 0) }
+	exit := func(ev *termbox.Event) *Event { return WidgetResult(Ok, 0) }
+
+	entryState := fsm.AddNamedState("entry", entry, Sigil{})
+	exitState := fsm.AddNamedState("exit", exit, Sigil{})
+	active := fsm.AddNamedEventState("active", f, Sigil{})
+
+	fsm.AddTransition(entryState, Ok, active)
+	fsm.AddTransition(entryState, Fail, exitState)
+	fsm.AddTransition(entryState, Repeat, active)
+	fsm.AddTransition(entryState, Nop, active)
+	fsm.AddTransition(active, Ok, active)
+	fsm.AddTransition(active, Fail, exitState)
+	fsm.AddTransition(active, Repeat, active)
+	fsm.AddTransition(active, Nop, active)
+
+	fsm.EntryState = entryState
+	fsm.ExitState = exitState
+	fsm.CurrentState = entryState
+
 	return fsm
 }
 
@@ -182,54 +325,177 @@ func WidgetResult(rc RetCode, n ...interface{}) *Event {
 // the widget writer's Refresh method to actually draw the widget
 // on screen.
 func (fsm *FiniteStateMachine) AddState(f WidgetStateFunc, glyph Sigil) FiniteState {
+	return fsm.AddNamedState("", f, glyph)
+}
+
+// AddNamedState is AddState for a state with a name.  Names are used
+// by the FSM builder and when reporting on the state machine.
+func (fsm *FiniteStateMachine) AddNamedState(name string, f WidgetStateFunc, glyph Sigil) FiniteState {
 	s := len(fsm.Sigil)
 	fsm.StateFunc = append(fsm.StateFunc, f)
 	fsm.Sigil = append(fsm.Sigil, glyph)
+	fsm.StateName = append(fsm.StateName, name)
 	return FiniteState(s)
 }
 
+// AddEventState adds a state whose function receives windigo events,
+// both those sent by the widget's container and termbox input events
+// wrapped by InputEvent.  This suits indicators, which change state
+// when their container sends them new data.
+func (fsm *FiniteStateMachine) AddEventState(f GadgetStateFunc, glyph Sigil) FiniteState {
+	return fsm.AddNamedEventState("", f, glyph)
+}
+
+func (fsm *FiniteStateMachine) AddNamedEventState(name string, f GadgetStateFunc, glyph Sigil) FiniteState {
+	s := fsm.AddNamedState(name, nil, glyph)
+	fsm.SetEventFunc(s, f)
+	return s
+}
+
+// SetEventFunc sets the function run when state s receives a windigo
+// event from the widget's container.
+func (fsm *FiniteStateMachine) SetEventFunc(s FiniteState, f GadgetStateFunc) {
+	for len(fsm.EventFunc) < len(fsm.StateFunc) {
+		fsm.EventFunc = append(fsm.EventFunc, nil)
+	}
+	fsm.EventFunc[s] = f
+}
+
+// Call runs the current state's function for e.  Termbox input
+// (WindEventInput) goes to the state's StateFunc, or if it has none, to
+// its EventFunc.  Other events go to the state's EventFunc.  Call
+// returns nil if the state has no function for e.
+func (fsm *FiniteStateMachine) Call(e *Event) *Event {
+	s := fsm.State()
+	if !fsm.validState(s) {
+		return nil
+	}
+	var ef GadgetStateFunc
+	if int(s) < len(fsm.EventFunc) {
+		ef = fsm.EventFunc[s]
+	}
+	if e.EventType == WindEventInput && e.Args != nil && e.Args.Tbox != nil &&
+		fsm.StateFunc[s] != nil {
+		return fsm.StateFunc[s](e.Args.Tbox)
+	}
+	if ef == nil {
+		return nil
+	}
+	return ef(e)
+}
+
+// Name returns the name of state s, or its number if it has no name.
+func (fsm *FiniteStateMachine) Name(s FiniteState) string {
+	if int(s) >= 0 && int(s) < len(fsm.StateName) && fsm.StateName[s] != "" {
+		return fsm.StateName[s]
+	}
+	return fmt.Sprintf("%d", int(s))
+}
+
+// StateByName returns the state named name.
+func (fsm *FiniteStateMachine) StateByName(name string) (FiniteState, bool) {
+	for i, n := range fsm.StateName {
+		if n == name {
+			return FiniteState(i), true
+		}
+	}
+	return FiniteState(-1), false
+}
+
 // There are no checks done here to ensure that destination states
 // actually exist.  If the destination state doesn't exist the state
 // machine's NextState function will return Fail when it attempts to
 // transition to  that state.  Source states are not a problem b/c
 // if the source state doesn't exist, the entry will simply never
-// be used.
+// be used.  Use Validate to check the finished transition table.
 func (fsm *FiniteStateMachine) AddTransition(src FiniteState, rc RetCode, dst FiniteState) {
 
-	fsm.Transitions = append(fsm.Transitions, Transition{src, rc, dst})
+	fsm.Transitions = append(fsm.Transitions,
+		Transition{SrcState: src, Rc: rc, DstState: dst})
+}
+
+// AddGuardedTransition adds a transition that is only taken if g returns
+// true.  Transitions for the same source state and RetCode are tried in
+// the order they were added, so a guarded transition should be added
+// before an unguarded fallback.  If every guard fails and there is no
+// fallback, the machine stays in its current state.  name is used when
+// reporting on the state machine.
+func (fsm *FiniteStateMachine) AddGuardedTransition(src FiniteState, rc RetCode, dst FiniteState, name string, g Guard) {
+
+	fsm.Transitions = append(fsm.Transitions,
+		Transition{SrcState: src, Rc: rc, DstState: dst, Guard: g, GuardName: name})
 }
 
+// NextState returns the state the machine moves to from the current
+// state on rc.  If the current state has no transition for rc, its
+// enclosing composite states are tried, innermost first.  A composite
+// destination is entered through its initial (or, with History, its
+// last active) child.  Guards are called with nil events.
 func (fsm *FiniteStateMachine) NextState(rc RetCode) (FiniteState, error) {
-	for i, _ := range fsm.Transitions {
-		if fsm.CurrentState == fsm.Transitions[i].SrcState &&
-			rc == fsm.Transitions[i].Rc {
-			dst := fsm.Transitions[i].DstState
-			if int(dst) < 0 || int(dst) >= len(fsm.StateFunc) {
-				err := errors.New("state machine transition table error: destination state out of range")
-				return fsm.CurrentState, err
-			}
-			return dst, nil
-		}
+	return fsm.nextState(rc, nil, nil)
+}
+
+func (fsm *FiniteStateMachine) nextState(rc RetCode, trigger, result *Event) (FiniteState, error) {
+	t := fsm.transition(fsm.CurrentState, rc, trigger, result)
+	if t == nil {
+		err := errors.New("state machine transition table error: no entry matching source state and given RetCode")
+		return fsm.CurrentState, err
 	}
-	err := errors.New("state machine transition table error: no entry matching source state and given RetCode")
-	return fsm.CurrentState, err
+	dst := t.DstState
+	if int(dst) < 0 || int(dst) >= len(fsm.StateFunc) {
+		err := errors.New("state machine transition table error: destination state out of range")
+		return fsm.CurrentState, err
+	}
+	return fsm.descend(dst), nil
+}
+
+// Advance moves the machine on rc, running the OnExit actions of the
+// states left and the OnEnter actions of the states entered.  Staying in
+// the same state runs no actions.  Guards are called with a nil trigger
+// and WidgetResult(rc) as the result.
+func (fsm *FiniteStateMachine) Advance(rc RetCode) (FiniteState, error) {
+	return fsm.Fire(nil, WidgetResult(rc))
+}
+
+// Fire advances the machine on the RetCode of result, the event a state
+// function returned when run on trigger.  Both are passed to the guards
+// of the transitions considered.
+func (fsm *FiniteStateMachine) Fire(trigger, result *Event) (FiniteState, error) {
+	src := fsm.CurrentState
+	dst, err := fsm.nextState(result.Result.Rc, trigger, result)
+	if err != nil {
+		return fsm.CurrentState, err
+	}
+	fsm.moveTo(dst)
+	fsm.record(TransitionRecord{Time: fsm.clock().Now(), Src: src,
+		Trigger: trigger, Result: result, Rc: result.Result.Rc, Dst: dst})
+	return dst, nil
 }
 
 // Getter and Setter methods.
 //
 func (fsm *FiniteStateMachine) SetState(s FiniteState) {
 	if fsm != nil {
-		fsm.CurrentState = s
+		fsm.setCurrent(s)
 	}
 }
 
+// State may be called from any goroutine.
 func (fsm *FiniteStateMachine) State() FiniteState {
 	if fsm != nil {
+		fsm.mu.Lock()
+		defer fsm.mu.Unlock()
 		return fsm.CurrentState
 	}
 	return FiniteState(-1)
 }
 
+func (fsm *FiniteStateMachine) setCurrent(s FiniteState) {
+	fsm.mu.Lock()
+	fsm.CurrentState = s
+	fsm.mu.Unlock()
+}
+
 func (fsm *FiniteStateMachine) Entry() FiniteState {
 	return fsm.EntryState
 }
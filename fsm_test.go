@@ -0,0 +1,47 @@
+package windigo
+
+import "testing"
+
+func dirty(o Object) bool {
+	render.Lock()
+	defer render.Unlock()
+
+	for _, d := range render.dirty {
+		if d == o {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOnEnterKeepsRedraw(t *testing.T) {
+	c, _ := NewCheckbox(NewRegion(0, 0, 10, 1), "check", 0, 0)
+	m := c.FSM()
+	entered := false
+	m.SetOnEnter(c.checked, func(FiniteState) { entered = true })
+
+	m.SetState(c.unchecked)
+	if _, err := m.Advance(Ok); err != nil {
+		t.Fatal(err)
+	}
+	if !entered {
+		t.Fatal("OnEnter action not run")
+	}
+	if !dirty(c) {
+		t.Fatal("widget not redrawn")
+	}
+}
+
+func TestAdvanceGuard(t *testing.T) {
+	m := NewFSM(nil, Sigil{}, Sigil{})
+	a0, _ := m.StateByName("active0")
+	a1, _ := m.StateByName("active1")
+	m.SetState(a0)
+	m.Transitions = nil
+	m.AddGuardedTransition(a0, Ok, a1, "result", func(fsm *FiniteStateMachine, trigger, result *Event) bool {
+		return trigger == nil && result != nil && result.Result.Rc == Ok
+	})
+	if s, err := m.Advance(Ok); err != nil || s != a1 {
+		t.Fatalf("in %s, %v; want active1", m.Name(s), err)
+	}
+}
@@ -0,0 +1,283 @@
+package windigo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The FSM builder defines a FiniteStateMachine by naming its states,
+// their Sigils and state functions, and listing its transitions, rather
+// than by chaining AddState and AddTransition calls with numeric states.
+// The machine is described either by an FSMSpec or by text like:
+//
+//	# a two position switch
+//	state entry  sigil=off func=init entry
+//	state off    func=active
+//	state on     func=active
+//	state exit   sigil=off func=done exit
+//
+//	entry --Ok--> off
+//	entry --Fail--> exit
+//	entry --Repeat--> entry
+//	entry --Nop--> entry
+//	off --Ok--> on
+//	off --Fail--> exit
+//	off --Repeat--> off
+//	off --Nop--> off
+//	on --Ok--> off
+//	on --Fail--> exit
+//	on --Repeat--> on
+//	on --Nop--> on
+//
+// A transition may also be timed, i.e. "on --250ms--> off".
+// A state's sigil and func default to the Sigil and state function
+// registered under the state's own name, except that a composite state,
+// whose function is never run, needs no func.  States are numbered in the
+// order they are declared.  "parent=<state>" makes a state a child of an
+// earlier declared composite state and "history" makes a composite
+// state resume its last active child (see fsmtree.go.)
+// "frames=<sigil>,<sigil>... every=<duration>" animates a state (see
+// SetFrames.)
+
+type StateSpec struct {
+	Name  string
+	Sigil string
+	Func  string
+	Entry bool
+	Exit  bool
+	// Enclosing composite state, if any.
+	Parent  string
+	History bool
+	// Animation frames, by Sigil name, shown every Every.
+	Frames []string
+	Every  time.Duration
+	// Line in the FSM text, 0 if the spec was not parsed from text.
+	Line int
+}
+
+type TransitionSpec struct {
+	Src string
+	Rc  RetCode
+	Dst string
+	// If After is not 0 this is a timed transition and Rc is unused.
+	After time.Duration
+	Line  int
+}
+
+func (t TransitionSpec) String() string {
+	if t.After > 0 {
+		return fmt.Sprintf("%s --%s--> %s", t.Src, t.After, t.Dst)
+	}
+	return fmt.Sprintf("%s --%s--> %s", t.Src, t.Rc, t.Dst)
+}
+
+type FSMSpec struct {
+	States      []StateSpec
+	Transitions []TransitionSpec
+}
+
+// specError formats an error for an FSM text line, if known.
+func specError(line int, format string, a ...interface{}) error {
+	msg := fmt.Sprintf(format, a...)
+	if line > 0 {
+		return fmt.Errorf("fsm line %d: %s", line, msg)
+	}
+	return fmt.Errorf("fsm: %s", msg)
+}
+
+// ParseFSM parses the FSM text described above into an FSMSpec.
+func ParseFSM(text string) (*FSMSpec, error) {
+
+	spec := new(FSMSpec)
+
+	for i, line := range strings.Split(text, "\n") {
+		n := i + 1
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "state" {
+			if len(fields) < 2 {
+				return nil, specError(n, "state without a name")
+			}
+			st := StateSpec{Name: fields[1], Line: n}
+			for _, f := range fields[2:] {
+				switch {
+				case f == "entry":
+					st.Entry = true
+				case f == "exit":
+					st.Exit = true
+				case f == "history":
+					st.History = true
+				case strings.HasPrefix(f, "parent="):
+					st.Parent = strings.TrimPrefix(f, "parent=")
+				case strings.HasPrefix(f, "sigil="):
+					st.Sigil = strings.TrimPrefix(f, "sigil=")
+				case strings.HasPrefix(f, "func="):
+					st.Func = strings.TrimPrefix(f, "func=")
+				case strings.HasPrefix(f, "frames="):
+					st.Frames = strings.Split(strings.TrimPrefix(f, "frames="), ",")
+				case strings.HasPrefix(f, "every="):
+					d, err := time.ParseDuration(strings.TrimPrefix(f, "every="))
+					if err != nil || d <= 0 {
+						return nil, specError(n, "state %q: bad frame interval %q", st.Name, f)
+					}
+					st.Every = d
+				default:
+					return nil, specError(n, "state %q: unknown attribute %q", st.Name, f)
+				}
+			}
+			if (len(st.Frames) > 0) != (st.Every > 0) {
+				return nil, specError(n, "state %q: frames= and every= go together", st.Name)
+			}
+			spec.States = append(spec.States, st)
+			continue
+		}
+
+		// src --Rc--> dst
+		if len(fields) != 3 || !strings.HasPrefix(fields[1], "--") ||
+			!strings.HasSuffix(fields[1], "-->") || len(fields[1]) < 6 {
+			return nil, specError(n, "expected \"state\" or \"src --Rc--> dst\", got %q",
+				strings.TrimSpace(line))
+		}
+		name := fields[1][2 : len(fields[1])-3]
+		t := TransitionSpec{Src: fields[0], Dst: fields[2], Line: n}
+		rc, err := ParseRetCode(name)
+		if err != nil {
+			d, derr := time.ParseDuration(name)
+			if derr != nil || d <= 0 {
+				return nil, specError(n, "%s --%s--> %s: %v", fields[0], name, fields[2], err)
+			}
+			t.After = d
+		}
+		t.Rc = rc
+		spec.Transitions = append(spec.Transitions, t)
+	}
+	return spec, nil
+}
+
+// Build creates the FiniteStateMachine described by spec, looking up
+// Sigils and state functions by name.
+func (spec *FSMSpec) Build(sigils map[string]Sigil, funcs map[string]WidgetStateFunc) (*FiniteStateMachine, error) {
+
+	fsm := new(FiniteStateMachine)
+	fsm.EntryState = FiniteState(-1)
+	fsm.ExitState = FiniteState(-1)
+
+	composite := make(map[string]bool)
+	for _, st := range spec.States {
+		if st.Parent != "" {
+			composite[st.Parent] = true
+		}
+	}
+
+	for _, st := range spec.States {
+		if _, ok := fsm.StateByName(st.Name); ok {
+			return nil, specError(st.Line, "state %q declared twice", st.Name)
+		}
+
+		sigilName := st.Sigil
+		if sigilName == "" {
+			sigilName = st.Name
+		}
+		glyph, ok := sigils[sigilName]
+		if !ok {
+			return nil, specError(st.Line, "state %q: no sigil %q", st.Name, sigilName)
+		}
+
+		funcName := st.Func
+		if funcName == "" {
+			funcName = st.Name
+		}
+		f, ok := funcs[funcName]
+		if (!ok || f == nil) && !(st.Func == "" && composite[st.Name]) {
+			return nil, specError(st.Line, "state %q: no state func %q", st.Name, funcName)
+		}
+
+		var s FiniteState
+		if st.Parent != "" {
+			p, ok := fsm.StateByName(st.Parent)
+			if !ok {
+				return nil, specError(st.Line, "state %q: parent %q must be declared first",
+					st.Name, st.Parent)
+			}
+			s = fsm.AddNamedSubState(p, st.Name, f, glyph)
+		} else {
+			s = fsm.AddNamedState(st.Name, f, glyph)
+		}
+		if st.History {
+			fsm.SetHistory(s, true)
+		}
+		if len(st.Frames) > 0 {
+			var frames []Glyph
+			for _, name := range st.Frames {
+				glyph, ok := sigils[name]
+				if !ok {
+					return nil, specError(st.Line, "state %q: no sigil %q", st.Name, name)
+				}
+				frames = append(frames, glyph.Glyph())
+			}
+			fsm.SetFrames(s, st.Every, frames...)
+		}
+
+		if st.Entry {
+			if fsm.EntryState >= 0 {
+				return nil, specError(st.Line, "state %q: %q is already the entry state",
+					st.Name, fsm.Name(fsm.EntryState))
+			}
+			fsm.EntryState = s
+		}
+		if st.Exit {
+			if fsm.ExitState >= 0 {
+				return nil, specError(st.Line, "state %q: %q is already the exit state",
+					st.Name, fsm.Name(fsm.ExitState))
+			}
+			fsm.ExitState = s
+		}
+	}
+
+	if fsm.EntryState < 0 {
+		return nil, specError(0, "no entry state")
+	}
+
+	for _, t := range spec.Transitions {
+		src, ok := fsm.StateByName(t.Src)
+		if !ok {
+			return nil, specError(t.Line, "%s: unknown state %q", t, t.Src)
+		}
+		dst, ok := fsm.StateByName(t.Dst)
+		if !ok {
+			return nil, specError(t.Line, "%s: unknown state %q", t, t.Dst)
+		}
+		if t.After > 0 {
+			fsm.AddTimeout(src, t.After, dst)
+		} else {
+			fsm.AddTransition(src, t.Rc, dst)
+		}
+	}
+
+	fsm.CurrentState = fsm.EntryState
+
+	if debugBuild {
+		err := fsm.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fsm, nil
+}
+
+// NewFSMFromText parses text and builds the FiniteStateMachine it
+// describes.
+func NewFSMFromText(text string, sigils map[string]Sigil, funcs map[string]WidgetStateFunc) (*FiniteStateMachine, error) {
+	spec, err := ParseFSM(text)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Build(sigils, funcs)
+}
@@ -0,0 +1,49 @@
+package windigo
+
+import (
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestBuildComposite(t *testing.T) {
+	text := `
+state entry  func=ok entry
+state busy
+state b1     parent=busy func=ok
+state b2     parent=busy func=ok
+state exit   func=ok exit
+
+entry --Ok--> busy
+entry --Fail--> exit
+entry --Repeat--> entry
+entry --Nop--> entry
+busy --Fail--> exit
+busy --Repeat--> busy
+busy --Nop--> busy
+b1 --Ok--> b2
+b2 --Ok--> b1
+`
+	ok := func(ev *termbox.Event) *Event { return WidgetResult(Ok) }
+	sigils := map[string]Sigil{"entry": {}, "busy": {}, "b1": {}, "b2": {}, "exit": {}}
+	funcs := map[string]WidgetStateFunc{"ok": ok}
+
+	m, err := NewFSMFromText(text, sigils, funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Advance(Ok); err != nil {
+		t.Fatal(err)
+	}
+	if m.Name(m.State()) != "b1" {
+		t.Fatalf("in %s, want b1", m.Name(m.State()))
+	}
+
+	// A leaf state still needs a func.
+	funcs = map[string]WidgetStateFunc{"ok": ok, "b2": ok}
+	bad := "state entry func=ok entry\nstate lone\nstate exit func=ok exit\n"
+	if _, err := NewFSMFromText(bad, map[string]Sigil{"entry": {}, "lone": {}, "exit": {}},
+		funcs); err == nil {
+		t.Fatal("built a leaf state without a func")
+	}
+}
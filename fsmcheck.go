@@ -0,0 +1,229 @@
+package windigo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError lists everything Validate found wrong with a state
+// machine.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "fsm: " + strings.Join(e.Problems, "; ")
+}
+
+// The RetCodes every state but the exit state should have a transition
+// for.
+var coveredRetCodes = []RetCode{Fail, Ok, Repeat, Nop}
+
+// Validate checks the state machine's tables for transitions to states
+// that don't exist, states that can't be reached from the entry state,
+// states from which the exit state can't be reached, states without a
+// transition for every RetCode and duplicate (src, rc) entries, the
+// later of which NextState would never use.  Transitions inherited from
+// composite states count, and a malformed hierarchy is reported.  It
+// returns nil or a *ValidationError.
+func (fsm *FiniteStateMachine) Validate() error {
+
+	var problems []string
+	report := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	n := len(fsm.StateFunc)
+	valid := func(s FiniteState) bool {
+		return int(s) >= 0 && int(s) < n
+	}
+
+	if n == 0 {
+		return &ValidationError{[]string{"no states"}}
+	}
+	if len(fsm.Sigil) != n {
+		report("%d states but %d sigils", n, len(fsm.Sigil))
+	}
+	for s, f := range fsm.StateFunc {
+		if f == nil && (s >= len(fsm.EventFunc) || fsm.EventFunc[s] == nil) &&
+			!fsm.Composite(FiniteState(s)) {
+			report("state %s has no state func", fsm.Name(FiniteState(s)))
+		}
+	}
+	if !valid(fsm.EntryState) {
+		report("entry state %d does not exist", int(fsm.EntryState))
+	}
+	if !valid(fsm.ExitState) {
+		report("exit state %d does not exist", int(fsm.ExitState))
+	}
+
+	for i := range fsm.Parent {
+		s := FiniteState(i)
+		p := fsm.Parent[i]
+		if p >= 0 && !valid(p) {
+			report("state %s: parent state %d does not exist", fsm.Name(s), int(p))
+		} else if len(fsm.ancestors(s)) > n {
+			report("state %s is its own ancestor", fsm.Name(s))
+		}
+	}
+	for i := range fsm.Initial {
+		s := FiniteState(i)
+		c := fsm.Initial[i]
+		if c >= 0 && (!valid(c) || fsm.ParentOf(c) != s) {
+			report("state %s: initial state %d is not its child", fsm.Name(s), int(c))
+		}
+	}
+
+	if len(fsm.Animations) > n {
+		report("%d states but %d animations", n, len(fsm.Animations))
+	}
+	for i, a := range fsm.Animations {
+		if len(a.Frames) > 1 && a.Interval <= 0 {
+			report("state %s: %d frames but no frame interval",
+				fsm.Name(FiniteState(i)), len(a.Frames))
+		}
+	}
+
+	type key struct {
+		src FiniteState
+		rc  RetCode
+	}
+	seen := make(map[key]bool)
+
+	for _, t := range fsm.Transitions {
+		edge := fmt.Sprintf("%s --%s--> %s", fsm.Name(t.SrcState), t.Rc,
+			fsm.Name(t.DstState))
+		if !valid(t.SrcState) {
+			report("%s: source state does not exist", edge)
+			continue
+		}
+		if !valid(t.DstState) {
+			report("%s: destination state does not exist", edge)
+			continue
+		}
+		k := key{t.SrcState, t.Rc}
+		if seen[k] {
+			report("%s: duplicate of an earlier unguarded %s entry for %s", edge,
+				t.Rc, fsm.Name(t.SrcState))
+			continue
+		}
+		if t.Guard == nil {
+			seen[k] = true
+		}
+	}
+
+	// Only leaf states are ever current.  Their transitions include
+	// those inherited from their enclosing states.
+	next := make([][]FiniteState, n)
+	prev := make([][]FiniteState, n)
+
+	for s := FiniteState(0); int(s) < n; s++ {
+		if fsm.Composite(s) {
+			continue
+		}
+		var missing []string
+		for _, rc := range coveredRetCodes {
+			ts := fsm.candidates(s, rc)
+			if len(ts) == 0 {
+				missing = append(missing, rc.String())
+				continue
+			}
+			// Any guard may pass.
+			for _, t := range ts {
+				if !valid(t.DstState) {
+					continue
+				}
+				d := fsm.initialLeaf(t.DstState)
+				next[s] = append(next[s], d)
+				prev[d] = append(prev[d], s)
+			}
+		}
+		if len(missing) > 0 && s != fsm.ExitState {
+			report("state %s has no transition for %s", fsm.Name(s),
+				strings.Join(missing, ", "))
+		}
+	}
+
+	for _, t := range fsm.Timeouts {
+		edge := fmt.Sprintf("%s --%s--> %s", fsm.Name(t.SrcState), t.After,
+			fsm.Name(t.DstState))
+		if !valid(t.SrcState) {
+			report("%s: source state does not exist", edge)
+			continue
+		}
+		if !valid(t.DstState) {
+			report("%s: destination state does not exist", edge)
+			continue
+		}
+		// A composite's timeout applies to all of its descendants.
+		d := fsm.initialLeaf(t.DstState)
+		for s := FiniteState(0); int(s) < n; s++ {
+			if fsm.Composite(s) {
+				continue
+			}
+			for _, a := range fsm.ancestors(s) {
+				if a == t.SrcState {
+					next[s] = append(next[s], d)
+					prev[d] = append(prev[d], s)
+					break
+				}
+			}
+		}
+	}
+
+	// A composite state is reached if any of its children is.
+	lift := func(reached []bool) {
+		for s := FiniteState(0); int(s) < n; s++ {
+			if reached[s] {
+				for _, a := range fsm.ancestors(s) {
+					reached[a] = true
+				}
+			}
+		}
+	}
+
+	if valid(fsm.EntryState) {
+		reached := reach(fsm.initialLeaf(fsm.EntryState), next)
+		lift(reached)
+		for s := FiniteState(0); int(s) < n; s++ {
+			if !reached[s] {
+				report("state %s is unreachable from entry state %s",
+					fsm.Name(s), fsm.Name(fsm.EntryState))
+			}
+		}
+	}
+	if valid(fsm.ExitState) {
+		reached := reach(fsm.initialLeaf(fsm.ExitState), prev)
+		lift(reached)
+		for s := FiniteState(0); int(s) < n; s++ {
+			if !reached[s] {
+				report("state %s cannot reach exit state %s",
+					fsm.Name(s), fsm.Name(fsm.ExitState))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{problems}
+	}
+	return nil
+}
+
+// reach returns the states reachable from s following edges.
+func reach(s FiniteState, edges [][]FiniteState) []bool {
+	reached := make([]bool, len(edges))
+	reached[s] = true
+	todo := []FiniteState{s}
+
+	for len(todo) > 0 {
+		s = todo[0]
+		todo = todo[1:]
+		for _, d := range edges[s] {
+			if !reached[d] {
+				reached[d] = true
+				todo = append(todo, d)
+			}
+		}
+	}
+	return reached
+}
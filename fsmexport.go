@@ -0,0 +1,213 @@
+package windigo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes the state machine as a Graphviz DOT digraph named
+// name.  Nodes are labeled with the state's name and Sigil, edges with
+// their RetCodes.  The entry state is drawn bold and the exit state with
+// a double border.  If markCurrent is set, the current state is filled.
+func (fsm *FiniteStateMachine) WriteDOT(w io.Writer, name string, markCurrent bool) error {
+
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "digraph %s {\n", dotQuote(name))
+	fmt.Fprintf(b, "\trankdir=LR;\n")
+	fmt.Fprintf(b, "\tnode [shape=box, fontname=monospace];\n")
+
+	current := fsm.State()
+	for i := range fsm.StateFunc {
+		if fsm.ParentOf(FiniteState(i)) < 0 {
+			fsm.writeDOTState(b, FiniteState(i), current, markCurrent, "\t")
+		}
+	}
+
+	for _, e := range fsm.edges() {
+		fmt.Fprintf(b, "\ts%d -> s%d [label=%s];\n", int(e.src), int(e.dst),
+			dotQuote(e.label))
+	}
+
+	fmt.Fprintf(b, "}\n")
+	return b.Flush()
+}
+
+// writeDOTState writes the node for state s, and a cluster holding it
+// and its children if it is composite.
+func (fsm *FiniteStateMachine) writeDOTState(b *bufio.Writer, s FiniteState, current FiniteState, markCurrent bool, indent string) {
+
+	composite := fsm.Composite(s)
+	if composite {
+		fmt.Fprintf(b, "%ssubgraph cluster_s%d {\n", indent, int(s))
+		fmt.Fprintf(b, "%s\tlabel=%s;\n", indent, dotQuote(fsm.Name(s)))
+		indent += "\t"
+	}
+
+	attrs := []string{"label=" + dotQuote(fsm.label(s, "\n"))}
+	var style []string
+	if composite {
+		style = append(style, "dashed")
+	}
+	if s == fsm.EntryState {
+		style = append(style, "bold")
+	}
+	if s == fsm.ExitState {
+		attrs = append(attrs, "peripheries=2")
+	}
+	if markCurrent && s == current {
+		style = append(style, "filled")
+		attrs = append(attrs, "fillcolor=yellow")
+	}
+	if len(style) > 0 {
+		attrs = append(attrs, "style="+dotQuote(strings.Join(style, ",")))
+	}
+	fmt.Fprintf(b, "%ss%d [%s];\n", indent, int(s), strings.Join(attrs, ", "))
+
+	if composite {
+		for i := range fsm.StateFunc {
+			if fsm.ParentOf(FiniteState(i)) == s {
+				fsm.writeDOTState(b, FiniteState(i), current, markCurrent, indent)
+			}
+		}
+		fmt.Fprintf(b, "%s}\n", indent[:len(indent)-1])
+	}
+}
+
+// WriteMermaid writes the state machine as a Mermaid state diagram.
+// The entry state is entered from, and the exit state leads to, the
+// diagram's start/end marker.  If markCurrent is set, the current state
+// is given the "current" class.
+func (fsm *FiniteStateMachine) WriteMermaid(w io.Writer, markCurrent bool) error {
+
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "stateDiagram-v2\n")
+
+	for i := range fsm.StateFunc {
+		fmt.Fprintf(b, "\ts%d : %s\n", i,
+			mermaidEscape(fsm.label(FiniteState(i), "<br/>")))
+	}
+	for i := range fsm.StateFunc {
+		if fsm.ParentOf(FiniteState(i)) < 0 {
+			fsm.writeMermaidComposite(b, FiniteState(i), "\t")
+		}
+	}
+	if fsm.validState(fsm.EntryState) {
+		fmt.Fprintf(b, "\t[*] --> s%d\n", int(fsm.EntryState))
+	}
+	for _, e := range fsm.edges() {
+		fmt.Fprintf(b, "\ts%d --> s%d : %s\n", int(e.src), int(e.dst), e.label)
+	}
+	if fsm.validState(fsm.ExitState) {
+		fmt.Fprintf(b, "\ts%d --> [*]\n", int(fsm.ExitState))
+	}
+	if current := fsm.State(); markCurrent && fsm.validState(current) {
+		fmt.Fprintf(b, "\tclassDef current fill:#ff0,stroke:#000\n")
+		fmt.Fprintf(b, "\tclass s%d current\n", int(current))
+	}
+	return b.Flush()
+}
+
+// writeMermaidComposite writes the nested state block of composite
+// state s.
+func (fsm *FiniteStateMachine) writeMermaidComposite(b *bufio.Writer, s FiniteState, indent string) {
+	if !fsm.Composite(s) {
+		return
+	}
+	fmt.Fprintf(b, "%sstate s%d {\n", indent, int(s))
+	if int(s) < len(fsm.Initial) && fsm.validState(fsm.Initial[s]) {
+		fmt.Fprintf(b, "%s\t[*] --> s%d\n", indent, int(fsm.Initial[s]))
+	}
+	for i := range fsm.StateFunc {
+		c := FiniteState(i)
+		if fsm.ParentOf(c) != s {
+			continue
+		}
+		if fsm.Composite(c) {
+			fsm.writeMermaidComposite(b, c, indent+"\t")
+		} else {
+			fmt.Fprintf(b, "%s\ts%d\n", indent, i)
+		}
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+func (fsm *FiniteStateMachine) validState(s FiniteState) bool {
+	return int(s) >= 0 && int(s) < len(fsm.StateFunc)
+}
+
+// label is a state's name followed by its Sigil (the first frame of an
+// animated state), with rows separated by nl.
+func (fsm *FiniteStateMachine) label(s FiniteState, nl string) string {
+	l := fsm.Name(s)
+	if int(s) < len(fsm.History) && fsm.History[s] {
+		l += " (H)"
+	}
+	if int(s) < len(fsm.Animations) && len(fsm.Animations[s].Frames) > 1 {
+		l += fmt.Sprintf(" (%d frames)", len(fsm.Animations[s].Frames))
+	}
+	look := fsm.look(s)
+	if int(s) < len(fsm.Animations) && len(fsm.Animations[s].Frames) > 0 {
+		look = fsm.Animations[s].Frames[0]
+	}
+	glyph := strings.TrimRight(look.String(), "\n")
+	if glyph != "" {
+		l += nl + strings.Replace(glyph, "\n", nl, -1)
+	}
+	return l
+}
+
+type edge struct {
+	src, dst FiniteState
+	label    string
+}
+
+// edges merges the transitions between the same pair of states into
+// one edge labeled with all of their RetCodes, i.e. "Repeat, Nop".
+// Guarded transitions carry their guard's name, i.e. "Ok [armed]".
+func (fsm *FiniteStateMachine) edges() []edge {
+	var edges []edge
+
+	for _, t := range fsm.Transitions {
+		label := t.Rc.String()
+		if t.Guard != nil {
+			name := t.GuardName
+			if name == "" {
+				name = "guard"
+			}
+			label += " [" + name + "]"
+		}
+		found := false
+		for i := range edges {
+			if edges[i].src == t.SrcState && edges[i].dst == t.DstState {
+				edges[i].label += ", " + label
+				found = true
+				break
+			}
+		}
+		if !found {
+			edges = append(edges, edge{t.SrcState, t.DstState, label})
+		}
+	}
+	for _, t := range fsm.Timeouts {
+		edges = append(edges, edge{t.SrcState, t.DstState, "after " + t.After.String()})
+	}
+	return edges
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// Mermaid state descriptions end at a newline and treat ':' specially.
+func mermaidEscape(s string) string {
+	s = strings.Replace(s, ":", "#58;", -1)
+	s = strings.Replace(s, "\n", " ", -1)
+	return s
+}
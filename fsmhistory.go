@@ -0,0 +1,182 @@
+package windigo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Each state machine keeps a bounded history of the transitions it
+// took and tells its observers about each one as it happens.  A
+// recorded history can be replayed against a freshly built machine of
+// the same design to reproduce what an operator saw.
+
+// How many transitions a state machine remembers by default.
+const defHistoryLen = 64
+
+type TransitionRecord struct {
+	Time time.Time
+	Src  FiniteState
+	// The event the state function was run on, and what it returned.
+	// Both are nil for timed transitions.
+	Trigger *Event
+	Result  *Event
+	Rc      RetCode
+	Dst     FiniteState
+	// Set for timed transitions, which have no RetCode.
+	Timed bool
+}
+
+type TransitionObserver func(TransitionRecord)
+
+type fsmHistory struct {
+	sync.Mutex
+	size      int
+	records   []TransitionRecord
+	next      int
+	observers map[int]TransitionObserver
+}
+
+// SetHistoryLen sets how many transitions are remembered.  0, or less,
+// turns the history off.
+func (fsm *FiniteStateMachine) SetHistoryLen(n int) {
+	if n < 0 {
+		n = 0
+	}
+	h := fsm.hist()
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.size = n
+	if len(h.records) > n {
+		h.records = h.records[len(h.records)-n:]
+	}
+}
+
+// TransitionHistory returns the remembered transitions, oldest first.
+func (fsm *FiniteStateMachine) TransitionHistory() []TransitionRecord {
+	h := fsm.hist()
+
+	h.Lock()
+	defer h.Unlock()
+
+	r := make([]TransitionRecord, len(h.records))
+	copy(r, h.records)
+	return r
+}
+
+// Observe calls f, on the goroutine running the machine, for every
+// transition taken.  The returned function removes the observer.
+func (fsm *FiniteStateMachine) Observe(f TransitionObserver) func() {
+	h := fsm.hist()
+
+	h.Lock()
+	defer h.Unlock()
+
+	id := h.next
+	h.next++
+	h.observers[id] = f
+
+	return func() {
+		h.Lock()
+		defer h.Unlock()
+		delete(h.observers, id)
+	}
+}
+
+func (fsm *FiniteStateMachine) hist() *fsmHistory {
+	fsm.mu.Lock()
+	defer fsm.mu.Unlock()
+
+	if fsm.history == nil {
+		fsm.history = &fsmHistory{
+			size:      defHistoryLen,
+			observers: make(map[int]TransitionObserver),
+		}
+	}
+	return fsm.history
+}
+
+// record remembers r and tells the observers about it.  Nop transitions
+// back to the same state, i.e. for every ignored event, are not
+// remembered, so they do not push real ones out of the history.
+func (fsm *FiniteStateMachine) record(r TransitionRecord) {
+	h := fsm.hist()
+
+	h.Lock()
+	idle := !r.Timed && r.Rc == Nop && r.Src == r.Dst
+	if h.size > 0 && !idle {
+		if len(h.records) >= h.size {
+			h.records = append(h.records[:0], h.records[len(h.records)-h.size+1:]...)
+		}
+		h.records = append(h.records, r)
+	}
+	var observers []TransitionObserver
+	for _, f := range h.observers {
+		observers = append(observers, f)
+	}
+	h.Unlock()
+
+	for _, f := range observers {
+		f(r)
+	}
+}
+
+// Replay drives the machine through a recorded history, normally one
+// taken from another instance of the same machine, and reports the
+// first record it does not reproduce.  The machine is first moved to
+// the source state of the first record.  Timed transitions are taken
+// directly rather than by waiting for their timers.
+func (fsm *FiniteStateMachine) Replay(records []TransitionRecord) error {
+
+	if len(records) > 0 && fsm.State() != records[0].Src {
+		fsm.moveTo(records[0].Src)
+	}
+
+	for i, r := range records {
+		if fsm.State() != r.Src {
+			return fmt.Errorf("replay record %d: in state %s, recorded %s",
+				i, fsm.Name(fsm.State()), fsm.Name(r.Src))
+		}
+
+		var dst FiniteState
+		var err error
+
+		if r.Timed {
+			dst, err = fsm.expireTo(r.Dst)
+		} else {
+			result := r.Result
+			if result == nil {
+				result = WidgetResult(r.Rc)
+			}
+			dst, err = fsm.Fire(r.Trigger, result)
+		}
+		if err != nil {
+			return fmt.Errorf("replay record %d: %v", i, err)
+		}
+		if dst != r.Dst {
+			return fmt.Errorf("replay record %d: %s went to %s, recorded %s",
+				i, fsm.Name(r.Src), fsm.Name(dst), fsm.Name(r.Dst))
+		}
+	}
+	return nil
+}
+
+// expireTo takes the timed transition out of the current state (or its
+// enclosing states) that leads to dst.
+func (fsm *FiniteStateMachine) expireTo(dst FiniteState) (FiniteState, error) {
+	src := fsm.State()
+	for _, a := range fsm.ancestors(src) {
+		for _, t := range fsm.Timeouts {
+			if t.SrcState == a && fsm.descend(t.DstState) == dst {
+				fsm.moveTo(dst)
+				fsm.record(TransitionRecord{Time: fsm.clock().Now(), Src: src,
+					Dst: dst, Timed: true})
+				return dst, nil
+			}
+		}
+	}
+	return src, fmt.Errorf("no timed transition from %s to %s", fsm.Name(src),
+		fsm.Name(dst))
+}
@@ -0,0 +1,76 @@
+package windigo
+
+import (
+	"testing"
+	"time"
+)
+
+// runHistory drives m from active0 to active1 and back by a timeout,
+// with an ignored event between.
+func runHistory(m *FiniteStateMachine, c *FakeClock, a1, a0 FiniteState) {
+	m.AddTimeout(a1, time.Second, a0)
+	m.Begin()
+	m.Advance(Ok)
+	m.Advance(Nop)
+	c.Advance(time.Second)
+	m.Expire()
+}
+
+func TestHistory(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	m, a0, a1 := newTimedFSM(t, c)
+	var seen int
+	stop := m.Observe(func(TransitionRecord) { seen++ })
+	runHistory(m, c, a1, a0)
+	stop()
+
+	h := m.TransitionHistory()
+	if len(h) != 2 {
+		t.Fatalf("%d records, want 2: %+v", len(h), h)
+	}
+	if h[0].Src != a0 || h[0].Dst != a1 || h[0].Rc != Ok || h[0].Timed {
+		t.Fatalf("record 0: %+v", h[0])
+	}
+	if h[1].Src != a1 || h[1].Dst != a0 || !h[1].Timed ||
+		!h[1].Time.Equal(time.Unix(1, 0)) {
+		t.Fatalf("record 1: %+v", h[1])
+	}
+	// Observers hear of the ignored event too.
+	if seen != 3 {
+		t.Fatalf("observed %d transitions, want 3", seen)
+	}
+
+	m.SetHistoryLen(1)
+	if h := m.TransitionHistory(); len(h) != 1 || !h[0].Timed {
+		t.Fatalf("after SetHistoryLen(1): %+v", h)
+	}
+	m.SetHistoryLen(-1)
+	m.Advance(Ok)
+	if h := m.TransitionHistory(); len(h) != 0 {
+		t.Fatalf("after SetHistoryLen(-1): %+v", h)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	m, a0, a1 := newTimedFSM(t, c)
+	runHistory(m, c, a1, a0)
+	h := m.TransitionHistory()
+
+	r, _, _ := newTimedFSM(t, NewFakeClock(time.Unix(0, 0)))
+	r.AddTimeout(a1, time.Second, a0)
+	r.SetState(a1)
+	if err := r.Replay(h); err != nil {
+		t.Fatal(err)
+	}
+	if r.State() != a0 {
+		t.Fatalf("in %s, want active0", r.Name(r.State()))
+	}
+
+	bad := append([]TransitionRecord(nil), h...)
+	bad[1].Dst = a1
+	r.SetState(a0)
+	if err := r.Replay(bad); err == nil {
+		t.Fatal("replayed a timed transition that does not exist")
+	}
+}
@@ -0,0 +1,195 @@
+package windigo
+
+import (
+	"sync"
+	"time"
+)
+
+// Timed transitions move a state machine on after it has spent a given
+// time in a state, i.e. a momentary button that flashes for 250ms or an
+// alarm that resets after 10s.  Entering a state arms a timer for each
+// of its timed transitions and leaving it disarms them.  The timers are
+// run by the machine's Clock, not by sleeping in the widget goroutine;
+// when one fires, the widget's InputEventMgr is woken and takes the
+// transition by calling Expire.  A composite state's timed transitions
+// are measured from when the composite state was entered.
+
+type TimedTransition struct {
+	SrcState FiniteState
+	After    time.Duration
+	DstState FiniteState
+}
+
+type fsmTimers struct {
+	sync.Mutex
+	// Armed timers by index into Timeouts.
+	armed map[int]armedTimer
+	fired []expiry
+	next  uint64
+	c     chan struct{}
+}
+
+type armedTimer struct {
+	t  Timer
+	id uint64
+}
+
+type expiry struct {
+	i  int
+	id uint64
+}
+
+// AddTimeout adds a transition from src to dst, taken once the machine
+// has been in src for d.
+func (fsm *FiniteStateMachine) AddTimeout(src FiniteState, d time.Duration, dst FiniteState) {
+	fsm.Timeouts = append(fsm.Timeouts, TimedTransition{src, d, dst})
+}
+
+func (fsm *FiniteStateMachine) clock() Clock {
+	if fsm.Clock != nil {
+		return fsm.Clock
+	}
+	return defClock
+}
+
+func (fsm *FiniteStateMachine) timers() *fsmTimers {
+	fsm.mu.Lock()
+	defer fsm.mu.Unlock()
+
+	if fsm.timing == nil {
+		fsm.timing = &fsmTimers{
+			armed: make(map[int]armedTimer),
+			c:     make(chan struct{}, 1),
+		}
+	}
+	return fsm.timing
+}
+
+// TimerC returns a channel that receives when a timed transition is
+// due.  The machine's owner should then call Expire.
+func (fsm *FiniteStateMachine) TimerC() <-chan struct{} {
+	return fsm.timers().c
+}
+
+// Expire takes the first due timed transition that is still armed.  It
+// returns true if the machine changed state.  Any others that are due
+// are left for the next call, and TimerC receives again.
+func (fsm *FiniteStateMachine) Expire() bool {
+	tm := fsm.timers()
+
+	for {
+		tm.Lock()
+		if len(tm.fired) == 0 {
+			tm.Unlock()
+			return false
+		}
+		e := tm.fired[0]
+		tm.fired = tm.fired[1:]
+		a, ok := tm.armed[e.i]
+		live := ok && a.id == e.id
+		if live {
+			delete(tm.armed, e.i)
+		}
+		tm.Unlock()
+
+		if !live {
+			// Disarmed after it fired.
+			continue
+		}
+		t := fsm.Timeouts[e.i]
+		if !fsm.validState(t.DstState) {
+			continue
+		}
+		src := fsm.CurrentState
+		dst := fsm.descend(t.DstState)
+		fsm.moveTo(dst)
+		fsm.record(TransitionRecord{Time: fsm.clock().Now(), Src: src,
+			Dst: dst, Timed: true})
+
+		// A transition that does not leave its source state, i.e. a
+		// timed self-transition, does not re-enter it, so start its
+		// timer again here.
+		for _, s := range fsm.ancestors(fsm.CurrentState) {
+			if s == t.SrcState {
+				fsm.armTimeout(e.i)
+				break
+			}
+		}
+
+		tm.Lock()
+		if len(tm.fired) > 0 {
+			select {
+			case tm.c <- struct{}{}:
+			default:
+			}
+		}
+		tm.Unlock()
+		return true
+	}
+}
+
+// arm starts the timers for the timed transitions out of s.
+func (fsm *FiniteStateMachine) arm(s FiniteState) {
+	for i, t := range fsm.Timeouts {
+		if t.SrcState == s {
+			fsm.armTimeout(i)
+		}
+	}
+}
+
+// armTimeout starts the timer for Timeouts[i], unless it is running.
+func (fsm *FiniteStateMachine) armTimeout(i int) {
+	tm := fsm.timers()
+
+	tm.Lock()
+	defer tm.Unlock()
+
+	if _, ok := tm.armed[i]; ok {
+		return
+	}
+	tm.next++
+	id := tm.next
+	tm.armed[i] = armedTimer{fsm.clock().AfterFunc(fsm.Timeouts[i].After, func() {
+		tm.Lock()
+		tm.fired = append(tm.fired, expiry{i, id})
+		tm.Unlock()
+		select {
+		case tm.c <- struct{}{}:
+		default:
+		}
+	}), id}
+}
+
+// Stop disarms every timed transition and stops the animation being
+// shown, then calls OnStop.  A widget's InputEventMgr calls it when it
+// returns.
+func (fsm *FiniteStateMachine) Stop() {
+	tm := fsm.timers()
+
+	tm.Lock()
+	for i, a := range tm.armed {
+		a.t.Stop()
+		delete(tm.armed, i)
+	}
+	tm.Unlock()
+
+	fsm.stillAll()
+	if fsm.OnStop != nil {
+		fsm.OnStop()
+	}
+}
+
+// disarm stops the timers for the timed transitions out of s.
+func (fsm *FiniteStateMachine) disarm(s FiniteState) {
+	tm := fsm.timers()
+
+	tm.Lock()
+	defer tm.Unlock()
+
+	for i, a := range tm.armed {
+		if fsm.Timeouts[i].SrcState == s {
+			a.t.Stop()
+			delete(tm.armed, i)
+		}
+	}
+}
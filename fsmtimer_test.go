@@ -0,0 +1,130 @@
+package windigo
+
+import (
+	"testing"
+	"time"
+)
+
+// newTimedFSM returns a two state round robin machine, in active0, run
+// by c.
+func newTimedFSM(t *testing.T, c *FakeClock) (*FiniteStateMachine, FiniteState, FiniteState) {
+	m := NewFSM(nil, Sigil{}, Sigil{})
+	m.Clock = c
+	a0, ok0 := m.StateByName("active0")
+	a1, ok1 := m.StateByName("active1")
+	if !ok0 || !ok1 {
+		t.Fatal("no active states")
+	}
+	m.SetState(a0)
+	return m, a0, a1
+}
+
+func TestTimeout(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	m, a0, a1 := newTimedFSM(t, c)
+	m.AddTimeout(a1, 250*time.Millisecond, a0)
+	m.Begin()
+
+	if _, err := m.Advance(Ok); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(249 * time.Millisecond)
+	if m.Expire() {
+		t.Fatal("expired early")
+	}
+	c.Advance(time.Millisecond)
+	select {
+	case <-m.TimerC():
+	default:
+		t.Fatal("TimerC did not receive")
+	}
+	if !m.Expire() || m.State() != a0 {
+		t.Fatalf("in %s, want active0", m.Name(m.State()))
+	}
+}
+
+func TestTimeoutDisarmed(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	m, a0, a1 := newTimedFSM(t, c)
+	m.AddTimeout(a0, 250*time.Millisecond, a1)
+	m.Begin()
+
+	c.Advance(100 * time.Millisecond)
+	m.Advance(Ok)
+	m.Advance(Ok)
+	c.Advance(150 * time.Millisecond)
+	if m.Expire() {
+		t.Fatal("expired a timer armed before active0 was left")
+	}
+	c.Advance(100 * time.Millisecond)
+	if !m.Expire() || m.State() != a1 {
+		t.Fatalf("in %s, want active1", m.Name(m.State()))
+	}
+}
+
+func TestTimeoutSelf(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	m, a0, _ := newTimedFSM(t, c)
+	m.AddTimeout(a0, 250*time.Millisecond, a0)
+	m.Begin()
+
+	for i := 0; i < 3; i++ {
+		c.Advance(250 * time.Millisecond)
+		if !m.Expire() {
+			t.Fatalf("tick %d did not expire", i)
+		}
+	}
+	if n := len(m.TransitionHistory()); n != 3 {
+		t.Fatalf("%d records, want 3", n)
+	}
+}
+
+func TestTimeoutSimultaneous(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	m, a0, a1 := newTimedFSM(t, c)
+	m.AddTimeout(a0, 100*time.Millisecond, a0)
+	m.AddTimeout(a0, 100*time.Millisecond, a1)
+	m.Begin()
+
+	c.Advance(100 * time.Millisecond)
+	<-m.TimerC()
+	if !m.Expire() || m.State() != a0 {
+		t.Fatalf("in %s, want active0", m.Name(m.State()))
+	}
+	select {
+	case <-m.TimerC():
+	default:
+		t.Fatal("TimerC did not receive for the second expiry")
+	}
+	if !m.Expire() || m.State() != a1 {
+		t.Fatalf("in %s, want active1", m.Name(m.State()))
+	}
+	if m.Expire() {
+		t.Fatal("expired again")
+	}
+}
+
+func TestTimeoutComposite(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	m := NewFSM(nil)
+	m.Clock = c
+	idle := m.AddNamedState("idle", nil, Sigil{})
+	busy := m.AddNamedState("busy", nil, Sigil{})
+	b1 := m.AddNamedSubState(busy, "b1", nil, Sigil{})
+	b2 := m.AddNamedSubState(busy, "b2", nil, Sigil{})
+	m.AddTransition(b1, Ok, b2)
+	m.AddTimeout(busy, 100*time.Millisecond, idle)
+	m.SetState(busy)
+	m.Begin()
+	if m.State() != b1 {
+		t.Fatalf("in %s, want b1", m.Name(m.State()))
+	}
+
+	// Moving between busy's children does not restart its timer.
+	c.Advance(50 * time.Millisecond)
+	m.Advance(Ok)
+	c.Advance(50 * time.Millisecond)
+	if !m.Expire() || m.State() != idle {
+		t.Fatalf("in %s, want idle", m.Name(m.State()))
+	}
+}
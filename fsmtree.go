@@ -0,0 +1,242 @@
+package windigo
+
+// Hierarchical state machines.
+//
+// A state may be made a child of a composite state with AddSubState.
+// The machine's CurrentState is always a leaf (a state without
+// children); its StateFunc is the one that is run.  A composite state's
+// transitions apply to all of its descendants that don't have their
+// own transition for a RetCode, so common transitions, i.e. Fail to
+// the exit state, need only be given once.  A transition to a composite
+// state enters its initial child, or the child that was last active if
+// the composite state has History.  OnEnter and OnExit actions run for
+// every state entered and left, outermost first on entry and innermost
+// first on exit.  A flat machine is simply one without composite
+// states.
+
+// StateAction is run when the state machine enters or exits state s.
+type StateAction func(s FiniteState)
+
+// AddSubState adds a state as a child of the composite state parent.
+// The first child added becomes parent's initial state.
+func (fsm *FiniteStateMachine) AddSubState(parent FiniteState, f WidgetStateFunc, glyph Sigil) FiniteState {
+	return fsm.AddNamedSubState(parent, "", f, glyph)
+}
+
+func (fsm *FiniteStateMachine) AddNamedSubState(parent FiniteState, name string, f WidgetStateFunc, glyph Sigil) FiniteState {
+	s := fsm.AddNamedState(name, f, glyph)
+	fsm.grow()
+	fsm.Parent[s] = parent
+	if fsm.validState(parent) && fsm.Initial[parent] < 0 {
+		fsm.Initial[parent] = s
+	}
+	return s
+}
+
+// SetInitial sets the child through which composite state s is entered.
+func (fsm *FiniteStateMachine) SetInitial(s, child FiniteState) {
+	fsm.grow()
+	fsm.Initial[s] = child
+}
+
+// SetHistory makes composite state s resume its last active child.
+func (fsm *FiniteStateMachine) SetHistory(s FiniteState, on bool) {
+	fsm.grow()
+	fsm.History[s] = on
+}
+
+func (fsm *FiniteStateMachine) SetOnEnter(s FiniteState, a StateAction) {
+	fsm.grow()
+	fsm.OnEnter[s] = a
+}
+
+func (fsm *FiniteStateMachine) SetOnExit(s FiniteState, a StateAction) {
+	fsm.grow()
+	fsm.OnExit[s] = a
+}
+
+// grow extends the hierarchy tables to cover every state.
+func (fsm *FiniteStateMachine) grow() {
+	n := len(fsm.StateFunc)
+	for len(fsm.Parent) < n {
+		fsm.Parent = append(fsm.Parent, FiniteState(-1))
+	}
+	for len(fsm.Initial) < n {
+		fsm.Initial = append(fsm.Initial, FiniteState(-1))
+	}
+	for len(fsm.History) < n {
+		fsm.History = append(fsm.History, false)
+	}
+	for len(fsm.OnEnter) < n {
+		fsm.OnEnter = append(fsm.OnEnter, nil)
+	}
+	for len(fsm.OnExit) < n {
+		fsm.OnExit = append(fsm.OnExit, nil)
+	}
+	for len(fsm.last) < n {
+		fsm.last = append(fsm.last, FiniteState(-1))
+	}
+}
+
+// ParentOf returns the composite state containing s, or -1.
+func (fsm *FiniteStateMachine) ParentOf(s FiniteState) FiniteState {
+	if int(s) >= 0 && int(s) < len(fsm.Parent) {
+		return fsm.Parent[s]
+	}
+	return FiniteState(-1)
+}
+
+// Composite reports whether s has children.
+func (fsm *FiniteStateMachine) Composite(s FiniteState) bool {
+	for _, p := range fsm.Parent {
+		if p == s && s >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestors returns s and its enclosing states, innermost first.
+// A malformed (cyclic) hierarchy is cut short.
+func (fsm *FiniteStateMachine) ancestors(s FiniteState) []FiniteState {
+	var a []FiniteState
+	for fsm.validState(s) && len(a) <= len(fsm.StateFunc) {
+		a = append(a, s)
+		s = fsm.ParentOf(s)
+	}
+	return a
+}
+
+// transition returns the transition taken from s on rc, looking in
+// s's enclosing states if s has none whose guard allows it.
+func (fsm *FiniteStateMachine) transition(s FiniteState, rc RetCode, trigger, result *Event) *Transition {
+	for _, t := range fsm.candidates(s, rc) {
+		if t.Guard == nil || t.Guard(fsm, trigger, result) {
+			return t
+		}
+	}
+	return nil
+}
+
+// candidates returns the transitions that might be taken from s on rc,
+// in the order they are tried, up to and including the first unguarded
+// one.
+func (fsm *FiniteStateMachine) candidates(s FiniteState, rc RetCode) []*Transition {
+	var ts []*Transition
+	for _, a := range fsm.ancestors(s) {
+		for i := range fsm.Transitions {
+			t := &fsm.Transitions[i]
+			if t.SrcState == a && t.Rc == rc {
+				ts = append(ts, t)
+				if t.Guard == nil {
+					return ts
+				}
+			}
+		}
+	}
+	return ts
+}
+
+// descend returns the leaf state entered when entering s.
+func (fsm *FiniteStateMachine) descend(s FiniteState) FiniteState {
+	for i := 0; i < len(fsm.StateFunc) && fsm.Composite(s); i++ {
+		next := FiniteState(-1)
+		if fsm.History != nil && int(s) < len(fsm.History) && fsm.History[s] &&
+			int(s) < len(fsm.last) {
+			next = fsm.last[s]
+		}
+		if !fsm.validState(next) && int(s) < len(fsm.Initial) {
+			next = fsm.Initial[s]
+		}
+		if !fsm.validState(next) {
+			break
+		}
+		s = next
+	}
+	return s
+}
+
+// initialLeaf is descend ignoring History, for static analysis.
+func (fsm *FiniteStateMachine) initialLeaf(s FiniteState) FiniteState {
+	for i := 0; i < len(fsm.StateFunc) && fsm.Composite(s); i++ {
+		if int(s) >= len(fsm.Initial) || !fsm.validState(fsm.Initial[s]) {
+			break
+		}
+		s = fsm.Initial[s]
+	}
+	return s
+}
+
+// moveTo makes dst the current state, running exit and entry actions.
+func (fsm *FiniteStateMachine) moveTo(dst FiniteState) {
+	src := fsm.CurrentState
+	if dst == src {
+		return
+	}
+
+	from := fsm.ancestors(src)
+	to := fsm.ancestors(dst)
+
+	common := make(map[FiniteState]bool)
+	for _, s := range to {
+		common[s] = false
+	}
+	for _, s := range from {
+		if _, ok := common[s]; ok {
+			common[s] = true
+		}
+	}
+
+	for _, s := range from {
+		if common[s] {
+			break
+		}
+		fsm.exit(s)
+	}
+
+	fsm.setCurrent(dst)
+
+	for i := len(to) - 1; i >= 0; i-- {
+		if common[to[i]] {
+			continue
+		}
+		fsm.enter(to[i])
+	}
+	if fsm.OnChange != nil {
+		fsm.OnChange()
+	}
+}
+
+// Begin runs the OnEnter actions of the current state and its enclosing
+// states, descending into the current state if it is composite.
+// InputEventMgr calls it before running the machine.
+func (fsm *FiniteStateMachine) Begin() {
+	fsm.setCurrent(fsm.descend(fsm.CurrentState))
+	to := fsm.ancestors(fsm.CurrentState)
+	for i := len(to) - 1; i >= 0; i-- {
+		fsm.enter(to[i])
+	}
+	if fsm.OnChange != nil {
+		fsm.OnChange()
+	}
+}
+
+func (fsm *FiniteStateMachine) enter(s FiniteState) {
+	if p := fsm.ParentOf(s); fsm.validState(p) {
+		fsm.grow()
+		fsm.last[p] = s
+	}
+	fsm.arm(s)
+	fsm.animate(s)
+	if int(s) < len(fsm.OnEnter) && fsm.OnEnter[s] != nil {
+		fsm.OnEnter[s](s)
+	}
+}
+
+func (fsm *FiniteStateMachine) exit(s FiniteState) {
+	fsm.disarm(s)
+	fsm.still(s)
+	if int(s) < len(fsm.OnExit) && fsm.OnExit[s] != nil {
+		fsm.OnExit[s](s)
+	}
+}
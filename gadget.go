@@ -25,6 +25,9 @@ type GadgetType struct {
 	managed bool
 	wg      sync.WaitGroup
 	Parent  Container
+
+	// Application EventHandlers called by EventMgr.
+	handlerTable
 }
 
 func NewGadget(r *Region, fg, bg Attribute) *GadgetType {
@@ -77,8 +80,7 @@ func (g *GadgetType) SetAncestor(p Container) {
 }
 
 func (g *GadgetType) Init() error {
-	g.wg.Add(1)
-	go g.EventMgr()
+	spawn(g.EventMgr)
 	return nil
 }
 
@@ -94,6 +96,15 @@ func (g *GadgetType) AddChild(o Object) {
 	g.Children = append(g.Children, o)
 }
 
+// PushEvent sends e to the gadget's container.
+func (g *GadgetType) PushEvent(e *Event) {
+	if len(g.Comm) == 0 {
+		return
+	}
+	publish(g.Comm[0].Yang, e)
+	g.Comm[0].Yang <- e
+}
+
 func (g *GadgetType) Managed() bool {
 	return g.managed
 }
@@ -103,9 +114,13 @@ func (g *GadgetType) SetManaged() {
 }
 
 func (g *GadgetType) Manage(o Object) error {
-	Manage(g, o)
+	err := Manage(g, o)
+	if err != nil {
+		return err
+	}
+	g.wg.Add(1)
 	o.Init()
-	Flush()
+	QueueRedraw(o)
 	return nil
 }
 
@@ -122,8 +137,11 @@ func (g *GadgetType) Refresh() {
 
 func (g *GadgetType) EventMgr() {
 	EventMgr(g)
+	waitTimeout(&g.wg, exitTimeout)
 	p := g.Ancestor()
-	p.Done()
+	if p != nil {
+		p.Done()
+	}
 }
 
 func (g *GadgetType) Done() {
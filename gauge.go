@@ -0,0 +1,328 @@
+package windigo
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// Full block, and the first of the eighth-blocks used for the partly
+// filled cell at the end of a gauge's bar.
+const gaugeFull rune = 0x2588  // '█' U+2588
+const gaugeLeft rune = 0x2590  // less n eighths: '▏' U+258F .. '▉' U+2589
+const gaugeLower rune = 0x2580 // plus n eighths: '▁' U+2581 .. '▇' U+2587
+
+// GaugeText is the text a gauge prints beside its bar.
+type GaugeText int
+
+const (
+	GaugeNoText GaugeText = iota
+	GaugePercent
+	GaugeValue
+)
+
+// GaugeInterval is how often an indeterminate gauge moves.
+var GaugeInterval = 100 * time.Millisecond
+
+// A Band colors a gauge's bar Fg while its value is at least From.
+type Band struct {
+	From float64
+	Fg   Attribute
+}
+
+// GaugeType is a horizontal or vertical bar showing a value in its
+// Range, drawn to an eighth of a cell with the Unicode block elements.
+// A horizontal gauge prints its Label to the left of the bar and its
+// Text to the right; a vertical one prints them on its top and bottom
+// rows.  The bar is drawn in the Fg of the last of Bands, which are in
+// increasing order of From, that the value reaches, or the gauge's Fg if
+// there is none.  A gauge whose value is NaN is indeterminate: a block
+// sweeps to and fro along the bar every GaugeInterval.  Send the gauge
+// WindEventSetValue to change its value; only the gauge is redrawn.
+type GaugeType struct {
+	WidgetType
+	Range
+	Orientation OrientationType
+	Label       string
+	Text        GaugeText
+	Format      string
+	Bands       []Band
+
+	mu    sync.Mutex
+	value float64
+	phase int
+	timer Timer
+	gen   uint64
+	// Set once the gauge's InputEventMgr has returned.
+	stopped bool
+}
+
+func NewGauge(r *Region, o OrientationType, rng Range, fg, bg Attribute) (*GaugeType, error) {
+
+	g := new(GaugeType)
+	g.X = r.X
+	g.Y = r.Y
+	g.W = r.W
+	g.H = r.H
+	g.Fg = fg
+	g.Bg = bg
+	g.kbd = -1
+	g.Range = rng
+	g.Orientation = o
+	g.Format = "%g"
+	g.value = rng.Min
+	g.Fsm = NewEventFSM(g.event)
+	g.Fsm.OnStop = g.stop
+	return g, nil
+}
+
+// NewProgressBar returns a horizontal gauge from 0 to 100 that prints
+// its value as a percentage.
+func NewProgressBar(r *Region, fg, bg Attribute) (*GaugeType, error) {
+	g, err := NewGauge(r, Horizontal, Range{0, 100, 0}, fg, bg)
+	if err != nil {
+		return nil, err
+	}
+	g.Text = GaugePercent
+	return g, nil
+}
+
+func (g *GaugeType) Init() error {
+	g.Start()
+
+	g.mu.Lock()
+	g.animate()
+	g.mu.Unlock()
+
+	return nil
+}
+
+func (g *GaugeType) event(e *Event) *Event {
+	if e.EventType == WindEventSetValue {
+		if v, ok := e.Payload.(float64); ok {
+			g.SetValue(v)
+		}
+	}
+	return WidgetResult(Nop)
+}
+
+func (g *GaugeType) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.value
+}
+
+// SetValue sets the value, or makes the gauge indeterminate if v is NaN,
+// and redraws the gauge.
+func (g *GaugeType) SetValue(v float64) {
+	g.mu.Lock()
+	if !math.IsNaN(v) {
+		v = g.Clamp(v)
+	}
+	g.value = v
+	g.animate()
+	g.mu.Unlock()
+
+	if g.Managed() {
+		QueueRedraw(g)
+	}
+}
+
+// SetIndeterminate makes the gauge indeterminate until its value is
+// next set.
+func (g *GaugeType) SetIndeterminate() {
+	g.SetValue(math.NaN())
+}
+
+// Indeterminate reports whether the gauge is indeterminate.
+func (g *GaugeType) Indeterminate() bool {
+	return math.IsNaN(g.Value())
+}
+
+// stop ends the sweep for good when the gauge exits.
+func (g *GaugeType) stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.stopped = true
+	g.animate()
+}
+
+// animate starts or stops the sweep to match the value.  g.mu is held.
+func (g *GaugeType) animate() {
+	on := math.IsNaN(g.value) && g.Managed() && !g.stopped
+	if on && g.timer != nil {
+		return
+	}
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	g.gen++
+	if on {
+		g.phase = 0
+		g.tick(g.gen)
+	}
+}
+
+// tick arms the timer for the next step of the sweep.  g.mu is held.
+func (g *GaugeType) tick(gen uint64) {
+	g.timer = g.Fsm.clock().AfterFunc(GaugeInterval, func() {
+		g.mu.Lock()
+		if g.gen != gen {
+			g.mu.Unlock()
+			return
+		}
+		g.phase++
+		g.tick(gen)
+		g.mu.Unlock()
+		QueueRedraw(g)
+	})
+}
+
+// text returns the text printed beside the bar for v.
+func (g *GaugeType) text(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	switch g.Text {
+	case GaugePercent:
+		return fmt.Sprintf("%3.0f%%", 100*g.fraction(v))
+	case GaugeValue:
+		return fmt.Sprintf(g.Format, v)
+	}
+	return ""
+}
+
+// textWidth returns the width kept for the text, the widest of the text
+// at either end of the range, so that the bar doesn't move.
+func (g *GaugeType) textWidth() int {
+	w := runewidth.StringWidth(g.text(g.Min))
+	if m := runewidth.StringWidth(g.text(g.Max)); m > w {
+		w = m
+	}
+	return w
+}
+
+func (g *GaugeType) fraction(v float64) float64 {
+	if g.Max <= g.Min {
+		return 0
+	}
+	return (v - g.Min) / (g.Max - g.Min)
+}
+
+// color returns the color of the bar at v.
+func (g *GaugeType) color(v float64) Attribute {
+	fg, _ := g.Colors()
+	for _, b := range g.Bands {
+		if v >= b.From {
+			fg = b.Fg
+		}
+	}
+	return fg
+}
+
+// cells returns the runes of a bar n cells long, from the minimum end.
+func (g *GaugeType) cells(v float64, phase, n int) []rune {
+	bar := make([]rune, n)
+	for i := range bar {
+		bar[i] = ' '
+	}
+	if n <= 0 {
+		return bar
+	}
+
+	if math.IsNaN(v) {
+		k := n / 4
+		if k < 1 {
+			k = 1
+		}
+		pos := 0
+		if period := 2 * (n - k); period > 0 {
+			pos = phase % period
+			if pos > n-k {
+				pos = period - pos
+			}
+		}
+		for i := pos; i < pos+k; i++ {
+			bar[i] = gaugeFull
+		}
+		return bar
+	}
+
+	eighths := int(math.Round(g.fraction(v) * float64(8*n)))
+	for i := 0; i < eighths/8; i++ {
+		bar[i] = gaugeFull
+	}
+	if r := eighths % 8; r > 0 {
+		if g.Orientation == Vertical {
+			bar[eighths/8] = gaugeLower + rune(r)
+		} else {
+			bar[eighths/8] = gaugeLeft - rune(r)
+		}
+	}
+	return bar
+}
+
+func (g *GaugeType) Refresh() {
+
+	if !g.Managed() {
+		return
+	}
+
+	g.mu.Lock()
+	v, phase := g.value, g.phase
+	g.mu.Unlock()
+
+	fg, bg := g.Colors()
+	bar := g.color(v)
+	w, h := g.Size()
+	text := g.text(v)
+
+	if g.Orientation == Vertical {
+		top, bottom := 0, h
+		if g.Label != "" {
+			drawLine(g, 0, truncate(g.Label, w, false), AlignCenter, fg, bg)
+			top++
+		}
+		if g.Text != GaugeNoText && bottom-1 > top {
+			bottom--
+			drawLine(g, bottom, text, AlignCenter, fg, bg)
+		}
+		n := bottom - top
+		for i, r := range g.cells(v, phase, n) {
+			for x := 0; x < w; x++ {
+				SetCell(g, x, bottom-1-i, r, bar, bg)
+			}
+		}
+		return
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			SetCell(g, x, y, ' ', fg, bg)
+		}
+	}
+	left, right := 0, w
+	if g.Label != "" {
+		left = drawText(g, 0, h/2, w, g.Label, fg, bg) + 1
+	}
+	if g.Text != GaugeNoText {
+		if tw := g.textWidth(); right-tw-1 > left {
+			right -= tw + 1
+			drawText(g, w-runewidth.StringWidth(text), h/2, tw, text, fg, bg)
+		}
+	}
+	if right < left {
+		right = left
+	}
+	for y := 0; y < h; y++ {
+		for i, r := range g.cells(v, phase, right-left) {
+			SetCell(g, left+i, y, r, bar, bg)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package windigo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGaugeSweepStops(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	root, down := newTestRoot()
+	g, _ := NewGauge(NewRegion(0, 0, 10, 1), Horizontal, Range{0, 100, 0}, 0, 0)
+	g.Fsm.Clock = c
+	g.SetIndeterminate()
+	if err := root.Manage(g); err != nil {
+		t.Fatal(err)
+	}
+	root.Init()
+
+	c.Advance(GaugeInterval)
+	g.mu.Lock()
+	phase := g.phase
+	g.mu.Unlock()
+	if phase != 1 {
+		t.Fatalf("phase %d, want 1", phase)
+	}
+
+	down <- NewEvent(WindEventExit)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		g.mu.Lock()
+		stopped := g.stopped && g.timer == nil
+		g.mu.Unlock()
+		if stopped {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the sweep was not stopped on exit")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Advance(10 * GaugeInterval)
+	g.SetIndeterminate()
+	c.Advance(10 * GaugeInterval)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.phase != 1 || g.timer != nil {
+		t.Fatalf("phase %d after exit, want 1", g.phase)
+	}
+}
@@ -0,0 +1,120 @@
+package windigo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// HistogramType is a BarChart of the number of samples in each of a
+// set of bins.  If Edges holds at least two values, in increasing order,
+// they are the edges of the bins, and samples beyond the first or last
+// edge are counted in the first or last bin.  Otherwise the range of the
+// samples is split into Bins equal bins.  Each bar is labelled with the
+// lower edge of its bin, printed with the chart's Format.  Send the
+// histogram WindEventAppend to add a sample.
+type HistogramType struct {
+	BarChartType
+	Edges []float64
+	Bins  int
+
+	smu     sync.Mutex
+	samples []float64
+}
+
+func NewHistogram(r *Region, o OrientationType, bins int, fg, bg Attribute) (*HistogramType, error) {
+
+	h := new(HistogramType)
+	h.init(r, o, []Series{{Fg: fg}}, fg, bg)
+	h.Bins = bins
+	h.Fsm = NewEventFSM(h.event)
+	return h, nil
+}
+
+func (h *HistogramType) event(e *Event) *Event {
+	if e.EventType == WindEventAppend {
+		if v, ok := e.Payload.(Sample); ok {
+			h.Add(v.Value)
+		}
+	}
+	return WidgetResult(Nop)
+}
+
+// Add adds samples and redraws the histogram.  NaN samples are ignored.
+func (h *HistogramType) Add(v ...float64) {
+	h.smu.Lock()
+	for _, x := range v {
+		if !math.IsNaN(x) {
+			h.samples = append(h.samples, x)
+		}
+	}
+	h.smu.Unlock()
+
+	h.Rebin()
+}
+
+// Reset discards the samples and redraws the histogram.
+func (h *HistogramType) Reset() {
+	h.smu.Lock()
+	h.samples = nil
+	h.smu.Unlock()
+
+	h.Rebin()
+}
+
+// Samples returns the samples.
+func (h *HistogramType) Samples() []float64 {
+	h.smu.Lock()
+	defer h.smu.Unlock()
+
+	return append([]float64(nil), h.samples...)
+}
+
+// Rebin counts the samples into the bins and redraws the histogram.  It
+// should be called after Edges or Bins is changed.
+func (h *HistogramType) Rebin() {
+	h.SetBars(h.bin(h.Samples()))
+}
+
+// edges returns the edges of the bins for samples.
+func (h *HistogramType) edges(samples []float64) []float64 {
+	if len(h.Edges) >= 2 {
+		return h.Edges
+	}
+	n := h.Bins
+	if n < 1 || len(samples) == 0 {
+		return nil
+	}
+	min, max := dataRange(0, 0, samples)
+	edges := make([]float64, n+1)
+	for i := range edges {
+		edges[i] = min + (max-min)*float64(i)/float64(n)
+	}
+	return edges
+}
+
+// bin returns the bars of the histogram of samples.
+func (h *HistogramType) bin(samples []float64) []Bar {
+	edges := h.edges(samples)
+	if len(edges) < 2 {
+		return nil
+	}
+
+	bars := make([]Bar, len(edges)-1)
+	for i := range bars {
+		bars[i] = Bar{fmt.Sprintf(h.Format, edges[i]), []float64{0}}
+	}
+	for _, v := range samples {
+		// The bin whose lower edge is the last at or below v.
+		i := sort.Search(len(edges), func(i int) bool { return edges[i] > v }) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(bars) {
+			i = len(bars) - 1
+		}
+		bars[i].Values[0]++
+	}
+	return bars
+}
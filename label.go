@@ -0,0 +1,112 @@
+package windigo
+
+import (
+	"strings"
+	"sync"
+)
+
+// LabelType is a widget that displays text.  The text is wrapped across
+// the label's height according to Wrap, each line is placed according to
+// Align, and text that does not fit is cut, ending in Ellipsis if
+// Ellipsis is set.  The text may be changed with SetText or by sending
+// the label a WindEventSetText event.
+type LabelType struct {
+	WidgetType
+
+	Align    Alignment
+	Wrap     WrapMode
+	Ellipsis bool
+
+	mu   sync.Mutex
+	text string
+}
+
+func NewLabel(r *Region, text string, fg, bg Attribute) (*LabelType, error) {
+
+	l := new(LabelType)
+	l.X = r.X
+	l.Y = r.Y
+	l.W = r.W
+	l.H = r.H
+	l.Fg = fg
+	l.Bg = bg
+	l.kbd = -1
+	l.text = text
+	l.Fsm = NewEventFSM(l.event)
+	return l, nil
+}
+
+func (l *LabelType) Init() error {
+	l.Start()
+	return nil
+}
+
+func (l *LabelType) event(e *Event) *Event {
+	if e.EventType == WindEventSetText {
+		s, _ := e.Payload.(string)
+		l.SetText(s)
+	}
+	return WidgetResult(Nop)
+}
+
+// Text returns the label's text.
+func (l *LabelType) Text() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.text
+}
+
+// SetText replaces the label's text and redraws it.
+func (l *LabelType) SetText(s string) {
+	l.mu.Lock()
+	l.text = s
+	l.mu.Unlock()
+
+	if l.Managed() {
+		QueueRedraw(l)
+	}
+}
+
+// Lines returns the text as it is laid out in the label, one string per
+// row.
+func (l *LabelType) Lines() []string {
+	l.mu.Lock()
+	text := l.text
+	l.mu.Unlock()
+
+	w, h := l.Size()
+	lines := wrapText(text, w, l.Wrap)
+
+	cut := len(lines) > h
+	if cut {
+		lines = lines[:h]
+	}
+	for i := range lines {
+		lines[i] = truncate(lines[i], w, l.Ellipsis)
+	}
+	if cut && h > 0 && l.Ellipsis {
+		last := strings.TrimRight(lines[h-1], " ")
+		lines[h-1] = truncate(last+string(Ellipsis), w, true)
+	}
+	return lines
+}
+
+func (l *LabelType) Refresh() {
+
+	if !l.Managed() {
+		return
+	}
+
+	_, h := l.Size()
+	lines := l.Lines()
+	fg, bg := l.Colors()
+
+	for y := 0; y < h; y++ {
+		s := ""
+		if y < len(lines) {
+			s = lines[y]
+		}
+		drawLine(l, y, s, l.Align, fg, bg)
+	}
+}
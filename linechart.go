@@ -0,0 +1,296 @@
+package windigo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// The first braille pattern, and the bit of each of a braille cell's
+// dots, by row and column.
+const brailleBlank rune = 0x2800 // '⠀' U+2800
+
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20},
+	{0x40, 0x80}}
+
+// Series is a line of a LineChart.
+type Series struct {
+	Name string
+	Fg   Attribute
+}
+
+// LineChartType plots one or more series of values as lines of braille
+// dots, two across and four down each cell.  The Y axis is labelled on
+// the left with the top, middle and bottom of the range, printed with
+// YFormat, and XLabel is printed centered under the X axis.  If any
+// series is named, a legend of the names in the series' colors takes
+// the top row.  The values are scaled between Min and Max, or if Max is
+// not more than Min, between the least and greatest values shown.  Each
+// dot column is a value; the chart keeps the last Window values of each
+// series, or if Window is 0, as many as fit, so once it is full each
+// value added scrolls the oldest off the left.  NaN values break the
+// line.  Send the chart WindEventAppend to add a value to a series.
+type LineChartType struct {
+	WidgetType
+	Series  []Series
+	Min     float64
+	Max     float64
+	Window  int
+	YFormat string
+	XLabel  string
+
+	mu   sync.Mutex
+	data [][]float64
+}
+
+func NewLineChart(r *Region, series []Series, fg, bg Attribute) (*LineChartType, error) {
+
+	if len(series) == 0 {
+		return nil, errors.New("NewLineChart: no series")
+	}
+
+	c := new(LineChartType)
+	c.X = r.X
+	c.Y = r.Y
+	c.W = r.W
+	c.H = r.H
+	c.Fg = fg
+	c.Bg = bg
+	c.kbd = -1
+	c.Series = append([]Series(nil), series...)
+	c.YFormat = "%g"
+	c.data = make([][]float64, len(series))
+	c.Fsm = NewEventFSM(c.event)
+	return c, nil
+}
+
+func (c *LineChartType) Init() error {
+	c.Start()
+	return nil
+}
+
+func (c *LineChartType) event(e *Event) *Event {
+	if e.EventType == WindEventAppend {
+		if v, ok := e.Payload.(Sample); ok {
+			c.Append(v.Series, v.Value)
+		}
+	}
+	return WidgetResult(Nop)
+}
+
+// Append adds values to the end of series i and redraws the chart.
+func (c *LineChartType) Append(i int, v ...float64) error {
+	c.mu.Lock()
+	if i < 0 || i >= len(c.data) {
+		c.mu.Unlock()
+		return errors.New("Append: no such series")
+	}
+	c.data[i] = window(append(c.data[i], v...), c.keep())
+	c.mu.Unlock()
+
+	if c.Managed() {
+		QueueRedraw(c)
+	}
+	return nil
+}
+
+// SetData replaces the values of series i and redraws the chart.
+func (c *LineChartType) SetData(i int, data []float64) error {
+	c.mu.Lock()
+	if i < 0 || i >= len(c.data) {
+		c.mu.Unlock()
+		return errors.New("SetData: no such series")
+	}
+	c.data[i] = window(append([]float64(nil), data...), c.keep())
+	c.mu.Unlock()
+
+	if c.Managed() {
+		QueueRedraw(c)
+	}
+	return nil
+}
+
+// Data returns the values kept for series i.
+func (c *LineChartType) Data(i int) []float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if i < 0 || i >= len(c.data) {
+		return nil
+	}
+	return append([]float64(nil), c.data[i]...)
+}
+
+// keep returns the number of values kept of each series.
+func (c *LineChartType) keep() int {
+	if c.Window > 0 {
+		return c.Window
+	}
+	w, _ := c.Size()
+	return 2 * w
+}
+
+// chartLayout is where the parts of a LineChart go.
+type chartLayout struct {
+	labels        [3]string // Y axis labels: top, middle, bottom
+	yw            int       // width of the Y axis labels
+	top, axis, xl int       // rows of the legend or plot top, X axis and X label
+	pw, ph        int       // size of the plot in cells
+}
+
+func (c *LineChartType) layout(min, max float64) chartLayout {
+	var l chartLayout
+	w, h := c.Size()
+
+	l.labels[0] = fmt.Sprintf(c.YFormat, max)
+	l.labels[1] = fmt.Sprintf(c.YFormat, (min+max)/2)
+	l.labels[2] = fmt.Sprintf(c.YFormat, min)
+	for _, s := range l.labels {
+		if sw := runewidth.StringWidth(s); sw > l.yw {
+			l.yw = sw
+		}
+	}
+
+	for _, s := range c.Series {
+		if s.Name != "" {
+			l.top = 1
+			break
+		}
+	}
+	l.xl = -1
+	l.axis = h - 1
+	if c.XLabel != "" {
+		l.xl = h - 1
+		l.axis = h - 2
+	}
+	l.ph = l.axis - l.top
+	l.pw = w - l.yw - 1
+	return l
+}
+
+// plot returns the braille dots of each cell of a plot pw by ph cells,
+// and the color of the series last drawn in each.
+func (c *LineChartType) plot(data [][]float64, min, max float64, pw, ph int) ([]rune, []Attribute) {
+	dots := make([]rune, pw*ph)
+	fgs := make([]Attribute, pw*ph)
+	dw, dh := 2*pw, 4*ph
+
+	set := func(x, y int, fg Attribute) {
+		if x < 0 || y < 0 || x >= dw || y >= dh {
+			return
+		}
+		i := (y/4)*pw + x/2
+		dots[i] |= brailleDots[y%4][x%2]
+		fgs[i] = fg
+	}
+	ydot := func(v float64) int {
+		f := (v - min) / (max - min)
+		f = math.Max(0, math.Min(1, f))
+		return dh - 1 - int(math.Round(f*float64(dh-1)))
+	}
+
+	for i, d := range data {
+		fg := c.Series[i].Fg
+		d = window(append([]float64(nil), d...), dw)
+		for x, v := range d {
+			if math.IsNaN(v) {
+				continue
+			}
+			y := ydot(v)
+			if x == 0 || math.IsNaN(d[x-1]) {
+				set(x, y, fg)
+				continue
+			}
+			// Join to the previous value.
+			y0 := ydot(d[x-1])
+			mid := (y0 + y) / 2
+			for yy := y0; yy != mid; yy += sign(mid - y0) {
+				set(x-1, yy, fg)
+			}
+			for yy := mid; yy != y; yy += sign(y - mid) {
+				set(x, yy, fg)
+			}
+			set(x, y, fg)
+		}
+	}
+	return dots, fgs
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+
+func (c *LineChartType) Refresh() {
+
+	if !c.Managed() {
+		return
+	}
+
+	c.mu.Lock()
+	data := make([][]float64, len(c.data))
+	for i := range c.data {
+		data[i] = append([]float64(nil), c.data[i]...)
+	}
+	c.mu.Unlock()
+
+	fg, bg := c.Colors()
+	w, h := c.Size()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			SetCell(c, x, y, ' ', fg, bg)
+		}
+	}
+
+	min, max := dataRange(c.Min, c.Max, data...)
+	l := c.layout(min, max)
+	if l.pw <= 0 || l.ph <= 0 {
+		return
+	}
+
+	if l.top > 0 {
+		x := l.yw + 1
+		for _, s := range c.Series {
+			if s.Name == "" {
+				continue
+			}
+			x = drawText(c, x, 0, w-x, string(OutlineChars[HB])+" ", s.Fg, bg)
+			x = drawText(c, x, 0, w-x, s.Name+"  ", fg, bg)
+		}
+	}
+
+	rows := [3]int{l.top, l.top + (l.ph-1)/2, l.axis - 1}
+	for i, y := range rows {
+		if i == 1 && (y == rows[0] || y == rows[2]) {
+			continue
+		}
+		s := l.labels[i]
+		drawText(c, l.yw-runewidth.StringWidth(s), y, l.yw, s, fg, bg)
+	}
+	for y := l.top; y < l.axis; y++ {
+		SetCell(c, l.yw, y, OutlineChars[VB], fg, bg)
+	}
+	SetCell(c, l.yw, l.axis, OutlineChars[BL], fg, bg)
+	for x := l.yw + 1; x < w; x++ {
+		SetCell(c, x, l.axis, OutlineChars[HB], fg, bg)
+	}
+	if l.xl >= 0 {
+		x := l.yw + 1 + alignOffset(c.XLabel, l.pw, AlignCenter)
+		drawText(c, x, l.xl, w-x, c.XLabel, fg, bg)
+	}
+
+	dots, fgs := c.plot(data, min, max, l.pw, l.ph)
+	for y := 0; y < l.ph; y++ {
+		for x := 0; x < l.pw; x++ {
+			i := y*l.pw + x
+			if dots[i] != 0 {
+				SetCell(c, l.yw+1+x, l.top+y, brailleBlank+dots[i], fgs[i], bg)
+			}
+		}
+	}
+}
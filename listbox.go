@@ -0,0 +1,406 @@
+package windigo
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	runewidth "github.com/mattn/go-runewidth"
+	termbox "github.com/nsf/termbox-go"
+)
+
+// TypeAheadTimeout is how long a ListBox waits after a key is typed for
+// the next key of the same search.
+var TypeAheadTimeout = time.Second
+
+// ListBoxType is a scrolling list of items, one per row, with a
+// scrollbar in its right column when there are more items than rows.
+// It has a cursor, which Up, Down, PgUp, PgDn, Home and End move while
+// the list has the keyboard focus.  Typing moves the cursor to the next
+// item starting with what has been typed, ignoring case; a pause of
+// TypeAheadTimeout starts a new search.  Clicking an item moves the
+// cursor to it and takes the focus, and the mouse wheel, or clicking the
+// scrollbar above or below its thumb, scrolls.
+//
+// A single select list selects the item under the cursor and sends
+// WindEventSelectionChange, with its index and text, to its container
+// whenever the cursor moves.  A Multi select list marks each item with
+// CheckboxOff or CheckboxOn; Space or a click toggles the item under the
+// cursor and sends WindEventMultiSelectionChange with the indexes of the
+// selected items.  Enter sends WindEventActivate with the index of the
+// item under the cursor.
+type ListBoxType struct {
+	WidgetType
+	Multi bool
+
+	mu       sync.Mutex
+	items    []string
+	selected []bool
+	cursor   int
+	top      int
+	typed    []rune
+	typedAt  time.Time
+}
+
+func NewListBox(r *Region, items []string, fg, bg Attribute) (*ListBoxType, error) {
+
+	l := new(ListBoxType)
+	l.X = r.X
+	l.Y = r.Y
+	l.W = r.W
+	l.H = r.H
+	l.Fg = fg
+	l.Bg = bg
+	l.kbd = -1
+	l.allowFocus = true
+	l.items = append([]string(nil), items...)
+	l.selected = make([]bool, len(items))
+	l.cursor = -1
+	l.Fsm = NewEventFSM(l.event)
+	return l, nil
+}
+
+func (l *ListBoxType) Init() error {
+
+	x, y := l.Loc()
+	w, h := l.Size()
+	p := l.Ancestor()
+
+	r := Region{TopLeft{x, y}, WidthHeight{w, h}, false, false, false}
+	c, err := RegClickable(p, r)
+	if err != nil {
+		return err
+	}
+	l.InputChan = append(l.InputChan, c)
+
+	l.Start()
+
+	return nil
+}
+
+// SetItems replaces the items, clearing the selection.
+func (l *ListBoxType) SetItems(items []string) {
+	l.mu.Lock()
+	l.items = append([]string(nil), items...)
+	l.selected = make([]bool, len(items))
+	l.cursor = -1
+	l.top = 0
+	l.mu.Unlock()
+
+	if l.Managed() {
+		QueueRedraw(l)
+	}
+}
+
+// Items returns the items.
+func (l *ListBoxType) Items() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]string(nil), l.items...)
+}
+
+// Cursor returns the index of the item under the cursor, or -1.
+func (l *ListBoxType) Cursor() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.cursor
+}
+
+// Selected returns the indexes of the selected items in increasing
+// order.
+func (l *ListBoxType) Selected() []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.selection()
+}
+
+// selection returns the indexes of the selected items.  l.mu is held.
+func (l *ListBoxType) selection() []int {
+	sel := []int{}
+	for i, on := range l.selected {
+		if on {
+			sel = append(sel, i)
+		}
+	}
+	return sel
+}
+
+// SetSelected selects the items at the indexes given, and no others,
+// without telling the container.  A single select list selects the
+// first, and moves the cursor to it.
+func (l *ListBoxType) SetSelected(indexes ...int) {
+	l.mu.Lock()
+	for i := range l.selected {
+		l.selected[i] = false
+	}
+	for _, i := range indexes {
+		if i < 0 || i >= len(l.items) {
+			continue
+		}
+		l.selected[i] = true
+		if !l.Multi {
+			l.cursor = i
+			l.show()
+			break
+		}
+	}
+	l.mu.Unlock()
+
+	if l.Managed() {
+		QueueRedraw(l)
+	}
+}
+
+// show scrolls the cursor into view.  l.mu is held.
+func (l *ListBoxType) show() {
+	_, n := l.Size()
+	if l.cursor >= 0 {
+		if l.cursor < l.top {
+			l.top = l.cursor
+		}
+		if l.cursor >= l.top+n {
+			l.top = l.cursor - n + 1
+		}
+	}
+	l.clamp()
+}
+
+// clamp keeps the list filled from the top row down, without moving the
+// view to the cursor.  l.mu is held.
+func (l *ListBoxType) clamp() {
+	_, n := l.Size()
+	if l.top > len(l.items)-n {
+		l.top = len(l.items) - n
+	}
+	if l.top < 0 {
+		l.top = 0
+	}
+}
+
+// moveTo moves the cursor to item i, selecting it in a single select
+// list.  l.mu is held.
+func (l *ListBoxType) moveTo(i int) {
+	if len(l.items) == 0 {
+		return
+	}
+	if i >= len(l.items) {
+		i = len(l.items) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	l.cursor = i
+	if !l.Multi {
+		for j := range l.selected {
+			l.selected[j] = j == i
+		}
+	}
+	l.show()
+}
+
+// search moves the cursor to the next item, from the cursor on, that
+// starts with what has been typed, after adding r.  Typing the same
+// letter again moves on to the next item starting with it.  l.mu is
+// held.
+func (l *ListBoxType) search(r rune) {
+	now := l.Fsm.clock().Now()
+	if now.Sub(l.typedAt) > TypeAheadTimeout {
+		l.typed = l.typed[:0]
+	}
+	l.typedAt = now
+	l.typed = append(l.typed, r)
+
+	prefix := strings.ToLower(string(l.typed))
+	from := l.cursor
+	if lr := strings.ToLower(string(r)); strings.Trim(prefix, lr) == "" {
+		// A new search, or the same letter again, starts at the item
+		// after the cursor.
+		prefix = lr
+		from++
+	}
+	if from < 0 {
+		from = 0
+	}
+	n := len(l.items)
+	for k := 0; k < n; k++ {
+		i := (from + k) % n
+		if strings.HasPrefix(strings.ToLower(l.items[i]), prefix) {
+			l.moveTo(i)
+			return
+		}
+	}
+}
+
+func (l *ListBoxType) event(e *Event) *Event {
+	if e.EventType != WindEventInput {
+		return WidgetResult(Nop)
+	}
+
+	ev := e.Args.Tbox
+	w, h := l.Size()
+
+	l.mu.Lock()
+	before := l.selection()
+	activate := false
+
+	switch ev.Type {
+	case termbox.EventMouse:
+		switch ev.Key {
+		case termbox.MouseWheelUp:
+			l.top -= 3
+		case termbox.MouseWheelDown:
+			l.top += 3
+		case termbox.MouseLeft:
+			l.ReqFocus()
+			if from, to := scrollbar(len(l.items), l.top, h); to > 0 && ev.MouseX >= w-1 {
+				// Clicking the scrollbar above or below the thumb
+				// pages up or down.
+				switch {
+				case ev.MouseY < from:
+					l.top -= h
+				case ev.MouseY >= to:
+					l.top += h
+				}
+			} else if i := l.top + ev.MouseY; i < len(l.items) {
+				l.moveTo(i)
+				if l.Multi {
+					l.selected[i] = !l.selected[i]
+				}
+			}
+		}
+		// Scrolling leaves the cursor where it is, even out of
+		// view; a click has already moved it into view.
+		l.clamp()
+	case termbox.EventKey:
+		switch ev.Key {
+		case termbox.KeyArrowUp:
+			l.moveTo(l.cursor - 1)
+		case termbox.KeyArrowDown:
+			l.moveTo(l.cursor + 1)
+		case termbox.KeyPgup:
+			l.moveTo(l.cursor - h)
+		case termbox.KeyPgdn:
+			l.moveTo(l.cursor + h)
+		case termbox.KeyHome:
+			l.moveTo(0)
+		case termbox.KeyEnd:
+			l.moveTo(len(l.items) - 1)
+		case termbox.KeyEnter:
+			activate = l.cursor >= 0
+		case termbox.KeySpace:
+			if l.Multi && l.cursor >= 0 {
+				l.selected[l.cursor] = !l.selected[l.cursor]
+			} else {
+				l.search(' ')
+			}
+		default:
+			if ev.Ch != 0 && ev.Key == 0 {
+				l.search(ev.Ch)
+			}
+		}
+	}
+	cursor := l.cursor
+	after := l.selection()
+	var label string
+	if len(after) > 0 {
+		label = l.items[after[0]]
+	}
+	l.mu.Unlock()
+
+	QueueRedraw(l)
+
+	switch {
+	case activate:
+		return NewEvent(WindEventActivate, cursor)
+	case equalInts(before, after):
+	case l.Multi:
+		return NewEvent(WindEventMultiSelectionChange, after)
+	case len(after) > 0:
+		return NewEvent(WindEventSelectionChange, Selection{after[0], label})
+	}
+	return WidgetResult(Nop)
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scrollbar returns the rows the scrollbar's thumb covers, from and up
+// to, for n items from top in h rows, or 0, 0 if they all fit.
+func scrollbar(n, top, h int) (int, int) {
+	if n <= h || h <= 0 {
+		return 0, 0
+	}
+	size := h * h / n
+	if size < 1 {
+		size = 1
+	}
+	from := top * (h - size) / (n - h)
+	return from, from + size
+}
+
+func (l *ListBoxType) Refresh() {
+
+	if !l.Managed() {
+		return
+	}
+
+	fg, bg := l.Colors()
+	w, h := l.Size()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	from, to := scrollbar(len(l.items), l.top, h)
+	tw := w
+	if to > 0 {
+		tw--
+		for y := 0; y < h; y++ {
+			r := OutlineChars[VB]
+			if y >= from && y < to {
+				r = gaugeFull
+			}
+			SetCell(l, tw, y, r, fg, bg)
+		}
+	}
+
+	for y := 0; y < h; y++ {
+		i := l.top + y
+		if i >= len(l.items) {
+			for x := 0; x < tw; x++ {
+				SetCell(l, x, y, ' ', fg, bg)
+			}
+			continue
+		}
+		s := l.items[i]
+		f := fg
+		if l.Multi {
+			mark := CheckboxOff
+			if l.selected[i] {
+				mark = CheckboxOn
+			}
+			s = mark + s
+			if i == l.cursor {
+				f |= AttrReverse
+			}
+		} else if l.selected[i] {
+			f |= AttrReverse
+		}
+		s = truncate(s, tw, runewidth.StringWidth(s) > tw)
+		x := drawText(l, 0, y, tw, s, f, bg)
+		for ; x < tw; x++ {
+			SetCell(l, x, y, ' ', f, bg)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package windigo
+
+import (
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestScrollbar(t *testing.T) {
+	tests := []struct {
+		n, top, h int
+		from, to  int
+	}{
+		{5, 0, 10, 0, 0},
+		{10, 0, 10, 0, 0},
+		{20, 0, 0, 0, 0},
+		{20, 0, 10, 0, 5},
+		{20, 5, 10, 2, 7},
+		{20, 10, 10, 5, 10},
+		{1000, 0, 10, 0, 1},
+		{1000, 990, 10, 9, 10},
+	}
+	for _, tt := range tests {
+		from, to := scrollbar(tt.n, tt.top, tt.h)
+		if from != tt.from || to != tt.to {
+			t.Errorf("scrollbar(%d, %d, %d) = %d, %d, want %d, %d", tt.n,
+				tt.top, tt.h, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestListBoxScroll(t *testing.T) {
+	items := make([]string, 20)
+	for i := range items {
+		items[i] = string(rune('a' + i))
+	}
+	l, _ := NewListBox(NewRegion(0, 0, 10, 5), items, 0, 0)
+	l.moveTo(0)
+	mouse := func(k termbox.Key, y int) {
+		l.event(InputEvent(&termbox.Event{Type: termbox.EventMouse, Key: k,
+			MouseX: 9, MouseY: y}))
+	}
+
+	// The wheel and the scrollbar move the view, not the cursor.
+	mouse(termbox.MouseWheelDown, 0)
+	if l.top != 3 || l.cursor != 0 {
+		t.Fatalf("wheel: top %d cursor %d, want 3 0", l.top, l.cursor)
+	}
+	mouse(termbox.MouseLeft, 4)
+	if l.top != 8 || l.cursor != 0 {
+		t.Fatalf("page: top %d cursor %d, want 8 0", l.top, l.cursor)
+	}
+	mouse(termbox.MouseWheelDown, 0)
+	mouse(termbox.MouseWheelDown, 0)
+	mouse(termbox.MouseWheelDown, 0)
+	if l.top != 15 {
+		t.Fatalf("top %d past the end, want 15", l.top)
+	}
+
+	// Moving the cursor brings it back into view.
+	l.event(InputEvent(&termbox.Event{Type: termbox.EventKey, Key: termbox.KeyArrowDown}))
+	if l.top != 1 || l.cursor != 1 {
+		t.Fatalf("key: top %d cursor %d, want 1 1", l.top, l.cursor)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !windigo_debug
+// +build !windigo_debug
+
+package windigo
+
+const debugBuild = false
@@ -16,7 +16,12 @@ func NewPanel(r *Region, fg, bg Attribute) *PanelType {
 }
 
 func (p *PanelType) Manage(o Object) error {
-	Manage(p, o)
+	err := Manage(p, o)
+	if err != nil {
+		return err
+	}
+	p.wg.Add(1)
 	o.Init()
+	QueueRedraw(o)
 	return nil
 }
@@ -0,0 +1,244 @@
+package windigo
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Widget state may be saved to a JSON file with SaveState and restored,
+// i.e. when the application restarts, with RestoreState.  The current
+// state of every named Object's state machine is saved, along with any
+// values registered for it with PersistValue.  Objects are matched by
+// their path (see ObjectPath), so they must be named the same way each
+// time the application runs.
+//
+// The file looks like:
+//
+//	{"Objects": {"main/mode": {"State": "active1", "Values": {"level": 5}}}}
+
+var ErrNoFSM = errors.New("object has no state machine")
+
+// How long RestoreState waits for each Object to change state.
+var restoreTimeout = time.Second
+
+type persistValue struct {
+	key string
+	ptr interface{}
+	mu  sync.Locker
+}
+
+type persistedType struct {
+	sync.Mutex
+	values map[Object][]persistValue
+}
+
+var persisted = persistedType{
+	values: make(map[Object][]persistValue),
+}
+
+type savedObject struct {
+	State  string                     `json:",omitempty"`
+	Values map[string]json.RawMessage `json:",omitempty"`
+}
+
+type savedState struct {
+	Objects map[string]savedObject
+}
+
+// PersistValue registers the value ptr points to, under key, to be saved
+// and restored along with Object o's state.  ptr must be a pointer to a
+// value encoding/json can marshal and unmarshal.  mu is held while the
+// value is read or written, from the goroutine calling SaveState or
+// RestoreState; it must be the lock the widget holds whenever it uses
+// the value.
+func PersistValue(o Object, key string, ptr interface{}, mu sync.Locker) error {
+	if mu == nil {
+		return errors.New("PersistValue: " + key + ": no lock")
+	}
+
+	persisted.Lock()
+	defer persisted.Unlock()
+
+	vals := persisted.values[o]
+	for i := range vals {
+		if vals[i].key == key {
+			vals[i] = persistValue{key, ptr, mu}
+			return nil
+		}
+	}
+	persisted.values[o] = append(vals, persistValue{key, ptr, mu})
+	return nil
+}
+
+// SaveState writes the state of all named Objects to the file at path.
+// The file is replaced atomically.
+func SaveState(path string) error {
+
+	saved := savedState{Objects: make(map[string]savedObject)}
+
+	registry.Lock()
+	paths := make(map[Object]string)
+	for o := range registry.names {
+		paths[o] = registry.path(o)
+	}
+	registry.Unlock()
+
+	for o, p := range paths {
+		var so savedObject
+		if s, ok := o.(Stateful); ok && s.FSM() != nil {
+			fsm := s.FSM()
+			so.State = fsm.Name(fsm.State())
+		}
+		vals, err := persistedValues(o)
+		if err != nil {
+			return errors.New("SaveState: " + p + ": " + err.Error())
+		}
+		so.Values = vals
+		if so.State == "" && len(so.Values) == 0 {
+			continue
+		}
+		saved.Objects[p] = so
+	}
+
+	b, err := json.MarshalIndent(&saved, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func persistedValues(o Object) (map[string]json.RawMessage, error) {
+	persisted.Lock()
+	vals := persisted.values[o]
+	persisted.Unlock()
+
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]json.RawMessage)
+	for _, v := range vals {
+		v.mu.Lock()
+		b, err := json.Marshal(v.ptr)
+		v.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		m[v.key] = b
+	}
+	return m, nil
+}
+
+// RestoreState reads a file written by SaveState and restores the saved
+// values and states of the Objects it names.  Values are restored first,
+// then each Object's state machine is sent WindEventSetState, so that
+// the exit and entry actions of the states involved are run by the
+// widget itself.  Widgets must be running (managed by their container)
+// by the time RestoreState is called.  Objects that no longer exist are
+// skipped; the first error is returned after everything else has been
+// restored.
+func RestoreState(path string) error {
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var saved savedState
+	err = json.Unmarshal(b, &saved)
+	if err != nil {
+		return errors.New("RestoreState: " + err.Error())
+	}
+
+	var first error
+	fail := func(p string, err error) {
+		if first == nil {
+			first = errors.New("RestoreState: " + p + ": " + err.Error())
+		}
+	}
+
+	for p, so := range saved.Objects {
+		o, err := Lookup(p)
+		if err != nil {
+			fail(p, err)
+			continue
+		}
+		err = restoreValues(o, so.Values)
+		if err != nil {
+			fail(p, err)
+		}
+		QueueRedraw(o)
+		if so.State == "" {
+			continue
+		}
+		r, err := Request(o, NewEvent(WindEventSetState, so.State), restoreTimeout)
+		if err == nil && r.Result.Rc == Fail {
+			err = r.Result.Err
+		}
+		if err != nil {
+			fail(p, err)
+		}
+	}
+	return first
+}
+
+func restoreValues(o Object, saved map[string]json.RawMessage) error {
+	persisted.Lock()
+	vals := persisted.values[o]
+	persisted.Unlock()
+
+	var first error
+	for _, v := range vals {
+		b, ok := saved[v.key]
+		if !ok {
+			continue
+		}
+		v.mu.Lock()
+		err := json.Unmarshal(b, v.ptr)
+		v.mu.Unlock()
+		if err != nil && first == nil {
+			first = errors.New(v.key + ": " + err.Error())
+		}
+	}
+	return first
+}
+
+// Restore moves the state machine directly to the state named name (or
+// numbered, for unnamed states), as saved by SaveState.  Exit actions of
+// the states left and entry actions of the states entered are run, as
+// for any other transition, but the move is not a transition of the
+// machine and is not recorded in its history.  Restore should be called
+// from the goroutine running the state machine, RestoreState arranges
+// this by sending the widget WindEventSetState.
+func (fsm *FiniteStateMachine) Restore(name string) error {
+	s, ok := fsm.StateByName(name)
+	if !ok {
+		n, err := strconv.Atoi(name)
+		if err != nil || !fsm.validState(FiniteState(n)) {
+			return errors.New("Restore: no state " + name)
+		}
+		s = FiniteState(n)
+	}
+	fsm.moveTo(fsm.descend(s))
+	return nil
+}
@@ -0,0 +1,38 @@
+package windigo
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPersistValues(t *testing.T) {
+	g := NewGadget(NewRegion(0, 0, 10, 1), 0, 0)
+	SetName(g, "persisted")
+	defer SetName(g, "")
+
+	var mu sync.Mutex
+	level := 5
+	if err := PersistValue(g, "level", &level, nil); err == nil {
+		t.Fatal("registered a value without a lock")
+	}
+	if err := PersistValue(g, "level", &level, &mu); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := SaveState(path); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	level = 9
+	mu.Unlock()
+	if err := RestoreState(path); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if level != 5 {
+		t.Fatalf("level %d, want 5", level)
+	}
+}
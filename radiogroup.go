@@ -0,0 +1,183 @@
+package windigo
+
+import "sync"
+
+// The marks drawn before a RadioGroup option's label.
+var (
+	RadioOff = "( ) "
+	RadioOn  = "(•) "
+)
+
+// RadioGroupType is a gadget holding a column of options, one per row,
+// of which at most one is selected.  Clicking an option, or pressing
+// Space or Enter while it has the keyboard focus, selects it.  The
+// options send WindEventActivate to the group, whose EventMgr enforces
+// the exclusivity and sends a single WindEventSelectionChange, carrying
+// the selected index and label, to the group's container.  Labels
+// beyond the group's height are dropped.
+type RadioGroupType struct {
+	GadgetType
+
+	mu       sync.Mutex
+	labels   []string
+	selected int
+	options  []*radioOption
+}
+
+type radioOption struct {
+	WidgetType
+
+	group *RadioGroupType
+	index int
+}
+
+func NewRadioGroup(r *Region, labels []string, fg, bg Attribute) *RadioGroupType {
+	g := new(RadioGroupType)
+	g.X = r.X
+	g.Y = r.Y
+	g.W = r.W
+	g.H = r.H
+	g.Fg = fg
+	g.Bg = bg
+	// One option per row: those that would not fit are left out.
+	if len(labels) > r.H && r.H >= 0 {
+		labels = labels[:r.H]
+	}
+	g.labels = append([]string(nil), labels...)
+	g.selected = -1
+	g.Handle(WindEventActivate, g.activate)
+	return g
+}
+
+// Init manages the group's options and starts its EventMgr.  The
+// options are managed first, as EventMgr reads the group's Comm.
+func (g *RadioGroupType) Init() error {
+	for i := range g.labels {
+		o := new(radioOption)
+		o.X = 0
+		o.Y = i
+		o.W = g.W
+		o.H = 1
+		o.Fg = g.Fg
+		o.Bg = g.Bg
+		o.kbd = -1
+		o.allowFocus = true
+		o.group = g
+		o.index = i
+		o.Fsm = NewEventFSM(o.event)
+		g.mu.Lock()
+		g.options = append(g.options, o)
+		g.mu.Unlock()
+		err := g.Manage(o)
+		if err != nil {
+			return err
+		}
+	}
+	return g.GadgetType.Init()
+}
+
+// Manage is GadgetType's Manage with the group, rather than its
+// embedded GadgetType, as the managed object's container.
+func (g *RadioGroupType) Manage(o Object) error {
+	err := Manage(g, o)
+	if err != nil {
+		return err
+	}
+	g.wg.Add(1)
+	o.Init()
+	QueueRedraw(o)
+	return nil
+}
+
+// Selected returns the index and label of the selected option, or -1
+// and "" if none is selected.
+func (g *RadioGroupType) Selected() (int, string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.selected < 0 {
+		return -1, ""
+	}
+	return g.selected, g.labels[g.selected]
+}
+
+// SetSelected selects option i (-1 for none) without telling the
+// container.
+func (g *RadioGroupType) SetSelected(i int) {
+	g.mu.Lock()
+	if i < -1 || i >= len(g.labels) {
+		i = -1
+	}
+	g.selected = i
+	options := g.options
+	g.mu.Unlock()
+
+	for _, o := range options {
+		QueueRedraw(o)
+	}
+}
+
+// activate is the group's handler for its options' WindEventActivate.
+func (g *RadioGroupType) activate(from Object, e *Event) {
+	if from == nil {
+		return
+	}
+	i, ok := e.Payload.(int)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	changed := i != g.selected && i >= 0 && i < len(g.labels)
+	g.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	g.SetSelected(i)
+	g.PushEvent(NewEvent(WindEventSelectionChange, Selection{i, g.labels[i]}))
+}
+
+func (o *radioOption) Init() error {
+
+	x, y := o.Loc()
+	w, h := o.Size()
+	p := o.Ancestor()
+
+	r := Region{TopLeft{x, y}, WidthHeight{w, h}, false, false, false}
+	c, err := RegClickable(p, r)
+	if err != nil {
+		return err
+	}
+	o.InputChan = append(o.InputChan, c)
+
+	o.Start()
+
+	return nil
+}
+
+func (o *radioOption) event(e *Event) *Event {
+	if e.EventType == WindEventInput && activated(&o.WidgetType, e.Args.Tbox) {
+		return NewEvent(WindEventActivate, o.index)
+	}
+	return WidgetResult(Nop)
+}
+
+func (o *radioOption) Refresh() {
+
+	if !o.Managed() {
+		return
+	}
+
+	g := o.group
+	g.mu.Lock()
+	mark := RadioOff
+	if g.selected == o.index {
+		mark = RadioOn
+	}
+	label := g.labels[o.index]
+	g.mu.Unlock()
+
+	fg, bg := o.Colors()
+	drawLine(o, 0, mark+label, AlignLeft, fg, bg)
+}
@@ -0,0 +1,192 @@
+package windigo
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// The registry associates names with Objects so that they may be found
+// from outside of the object tree, i.e. by the bridge.  An Object's path
+// is the names of its named ancestors and itself joined by '/',
+// e.g. "main/status/ok".  Unnamed ancestors do not contribute to the path.
+type registryType struct {
+	sync.Mutex
+	names map[Object]string
+	// The child Object at the other end of a container's comm[n].Yin.
+	sources map[chan *Event]Object
+	// Container EventMgr wakeup channels, keyed by the container's
+	// comm[0].Yin.
+	wakeups map[chan *Event]chan struct{}
+	// Events a Request took from a child's output, held for the
+	// container's EventMgr, keyed by the container's comm[0].Yin.
+	held map[chan *Event][]heldEvent
+}
+
+type heldEvent struct {
+	from Object
+	e    *Event
+}
+
+var registry = registryType{
+	names:   make(map[Object]string),
+	sources: make(map[chan *Event]Object),
+	wakeups: make(map[chan *Event]chan struct{}),
+	held:    make(map[chan *Event][]heldEvent),
+}
+
+// SetName registers an Object under name.  Names should not contain '/'.
+// Setting an empty name removes the Object from the registry.
+func SetName(o Object, name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if name == "" {
+		delete(registry.names, o)
+		return
+	}
+	registry.names[o] = name
+}
+
+// Name returns the name the Object was registered with, or "".
+func Name(o Object) string {
+	registry.Lock()
+	defer registry.Unlock()
+
+	return registry.names[o]
+}
+
+// ObjectPath returns the '/' separated path of names from the outermost
+// named ancestor down to the Object itself.
+func ObjectPath(o Object) string {
+	registry.Lock()
+	defer registry.Unlock()
+
+	return registry.path(o)
+}
+
+func (r *registryType) path(o Object) string {
+	var names []string
+
+	for o != nil {
+		if name, ok := r.names[o]; ok {
+			names = append([]string{name}, names...)
+		}
+		p := o.Ancestor()
+		if p == nil {
+			break
+		}
+		o = p
+	}
+	return strings.Join(names, "/")
+}
+
+// Lookup returns the Object whose path or name is given.  A bare name
+// is accepted only if it is unique.
+func Lookup(path string) (Object, error) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	var found Object
+	n := 0
+
+	for o, name := range registry.names {
+		if registry.path(o) == path {
+			return o, nil
+		}
+		if name == path {
+			found = o
+			n++
+		}
+	}
+	if n == 1 {
+		return found, nil
+	}
+	if n > 1 {
+		return nil, errors.New("Lookup: ambiguous name " + path)
+	}
+	return nil, errors.New("Lookup: no object named " + path)
+}
+
+// Objects returns the paths of all registered Objects.
+func Objects() []string {
+	registry.Lock()
+	defer registry.Unlock()
+
+	var paths []string
+	for o := range registry.names {
+		paths = append(paths, registry.path(o))
+	}
+	return paths
+}
+
+func (r *registryType) setSource(c chan *Event, o Object) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.sources[c] = o
+}
+
+// source returns the child Object that sends on c, or nil.
+func (r *registryType) source(c chan *Event) Object {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.sources[c]
+}
+
+func (r *registryType) wakeup(c chan *Event) chan struct{} {
+	r.Lock()
+	defer r.Unlock()
+
+	w, ok := r.wakeups[c]
+	if !ok {
+		w = make(chan struct{}, 1)
+		r.wakeups[c] = w
+	}
+	return w
+}
+
+// forget drops the entries for the comm channels of a container whose
+// EventMgr has returned.
+func (r *registryType) forget(comm []IChing) {
+	r.Lock()
+	defer r.Unlock()
+
+	if len(comm) > 0 {
+		delete(r.wakeups, comm[0].Yin)
+		delete(r.held, comm[0].Yin)
+	}
+	for i := 1; i < len(comm); i++ {
+		delete(r.sources, comm[i].Yin)
+	}
+}
+
+// hold keeps e, sent by from, for the EventMgr of the container whose
+// comm[0].Yin is c, and wakes it.
+func (r *registryType) hold(c chan *Event, from Object, e *Event) {
+	r.Lock()
+	r.held[c] = append(r.held[c], heldEvent{from, e})
+	r.Unlock()
+
+	r.kick(c)
+}
+
+// release returns, and forgets, the events held for the container
+// whose comm[0].Yin is c.
+func (r *registryType) release(c chan *Event) []heldEvent {
+	r.Lock()
+	defer r.Unlock()
+
+	h := r.held[c]
+	delete(r.held, c)
+	return h
+}
+
+// kick wakes the EventMgr of the container whose comm[0].Yin is c.
+func (r *registryType) kick(c chan *Event) {
+	select {
+	case r.wakeup(c) <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,148 @@
+package windigo
+
+import (
+	"errors"
+	"sync"
+)
+
+// termbox is not safe for concurrent use and SetCell walks the shared
+// parent pointers of the object tree, so all drawing and any mutation of
+// objects that are drawn should happen on a single goroutine.  Init
+// starts that goroutine; other goroutines hand it work with QueueUpdate,
+// QueueUpdateDraw and QueueRedraw.  Queued work is run in order.  Redraws
+// requested while work is pending are coalesced into one redraw and one
+// Flush.  When the UI shuts down, the work already queued is run before
+// the render goroutine returns; work queued after that is refused.
+type renderQueue struct {
+	sync.Mutex
+	updates []func()
+	// Redraw the whole screen after the updates.
+	draw bool
+	// Objects to redraw after the updates, unless draw is set.
+	dirty []Object
+	wake  chan struct{}
+	// Set once the render goroutine has returned.
+	closed bool
+}
+
+var render = renderQueue{wake: make(chan struct{}, 1)}
+
+var ErrClosed = errors.New("render goroutine has returned")
+
+// QueueUpdate runs f on the render goroutine.  It never blocks, so it
+// may also be called from the render goroutine itself.  It returns
+// ErrClosed, and f is not run, if the UI has shut down.
+func QueueUpdate(f func()) error {
+	render.Lock()
+	if render.closed {
+		render.Unlock()
+		return ErrClosed
+	}
+	render.updates = append(render.updates, f)
+	render.Unlock()
+	render.kick()
+	return nil
+}
+
+// QueueUpdateDraw runs f (which may be nil) on the render goroutine and
+// then redraws the whole screen.  Like QueueUpdate, it returns ErrClosed
+// if the UI has shut down.
+func QueueUpdateDraw(f func()) error {
+	render.Lock()
+	if render.closed {
+		render.Unlock()
+		return ErrClosed
+	}
+	if f != nil {
+		render.updates = append(render.updates, f)
+	}
+	render.draw = true
+	render.Unlock()
+	render.kick()
+	return nil
+}
+
+// QueueRedraw redraws o (i.e. calls its Refresh) on the render
+// goroutine, without redrawing its container.
+func QueueRedraw(o Object) {
+	render.Lock()
+	if render.closed {
+		render.Unlock()
+		return
+	}
+	found := false
+	for _, d := range render.dirty {
+		if d == o {
+			found = true
+			break
+		}
+	}
+	if !found {
+		render.dirty = append(render.dirty, o)
+	}
+	render.Unlock()
+	render.kick()
+}
+
+func (q *renderQueue) kick() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop is the render goroutine.
+func (q *renderQueue) loop() {
+	for {
+		select {
+		case <-q.wake:
+		case <-screen.ctx.Done():
+			q.drain()
+			return
+		}
+
+		q.Lock()
+		updates := q.updates
+		draw := q.draw
+		dirty := q.dirty
+		q.updates = nil
+		q.draw = false
+		q.dirty = nil
+		q.Unlock()
+
+		for _, f := range updates {
+			f()
+		}
+
+		if draw && len(screen.windows) > 0 {
+			// WindowType.Refresh flushes.
+			screen.windows[0].Refresh()
+		} else if len(dirty) > 0 {
+			for _, o := range dirty {
+				o.Refresh()
+			}
+			Flush()
+		}
+	}
+}
+
+// drain runs the updates still queued, and any they queue, and then
+// closes the queue.
+func (q *renderQueue) drain() {
+	for {
+		q.Lock()
+		updates := q.updates
+		q.updates = nil
+		if len(updates) == 0 {
+			q.closed = true
+			q.dirty = nil
+			q.Unlock()
+			return
+		}
+		q.Unlock()
+
+		for _, f := range updates {
+			f()
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package windigo
+
+import "testing"
+
+func TestRenderDrain(t *testing.T) {
+	var q renderQueue
+	var ran []int
+	q.updates = []func(){
+		func() { ran = append(ran, 1) },
+		func() {
+			ran = append(ran, 2)
+			// Work queued by queued work is run too.
+			q.Lock()
+			q.updates = append(q.updates, func() { ran = append(ran, 3) })
+			q.Unlock()
+		},
+	}
+	q.drain()
+	if len(ran) != 3 || ran[2] != 3 {
+		t.Fatalf("ran %v, want [1 2 3]", ran)
+	}
+	if !q.closed || len(q.updates) != 0 {
+		t.Fatal("queue not closed")
+	}
+}
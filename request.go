@@ -0,0 +1,183 @@
+package windigo
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The IChing Yin/Yang pair carries events in one direction at a time.
+// Request layers a request/reply exchange on top of it: the request is
+// sent down the Object's Yin with a new Id and a Deadline, the Object
+// answers with Respond, and the reply, recognized by its ReplyTo, is
+// handed back to the waiting Request.  The container's EventMgr may be
+// the goroutine waiting, i.e. when an EventHandler queries a child, so
+// while it waits Request takes the Object's output itself: replies are
+// delivered and anything else is held for the EventMgr to dispatch.
+
+var (
+	ErrTimeout = errors.New("request timed out")
+	ErrNoReply = errors.New("object does not answer requests")
+)
+
+var lastRequestId uint64
+
+type pendingType struct {
+	sync.Mutex
+	replies map[uint64]chan *Event
+}
+
+var pending = pendingType{replies: make(map[uint64]chan *Event)}
+
+// Request sends e to the managed Object o and waits up to timeout for
+// its reply.  If the reply's Result.Rc is Fail, the reply is returned
+// along with its Result.Err.  Request may be called from any goroutine,
+// including an EventHandler of o's container.
+func Request(o Object, e *Event, timeout time.Duration) (*Event, error) {
+
+	e.Id = atomic.AddUint64(&lastRequestId, 1)
+	e.Deadline = time.Now().Add(timeout)
+
+	c := make(chan *Event, 1)
+	pending.Lock()
+	pending.replies[e.Id] = c
+	pending.Unlock()
+
+	defer func() {
+		pending.Lock()
+		delete(pending.replies, e.Id)
+		pending.Unlock()
+	}()
+
+	r, err := exchange(o, e, c, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if r.Result != nil && r.Result.Rc == Fail {
+		err = r.Result.Err
+		if err == nil {
+			err = errors.New("request failed")
+		}
+		return r, err
+	}
+	return r, nil
+}
+
+// Send sends e to the managed Object o, as o's container would, and
+// returns once o has accepted it, or ErrTimeout if it has not within
+// timeout.  Like Request, it may be called from any goroutine.
+func Send(o Object, e *Event, timeout time.Duration) error {
+	_, err := exchange(o, e, nil, timeout)
+	return err
+}
+
+// Query asks o for its current value with a WindEventQuery request.
+// Containers do not answer queries, so for one Query returns ErrNoReply
+// at once.
+func Query(o Object, timeout time.Duration) (*Event, error) {
+	if _, ok := o.(Container); ok {
+		return nil, ErrNoReply
+	}
+	return Request(o, NewEvent(WindEventQuery), timeout)
+}
+
+// exchange sends e down o's Yin and, if c is not nil, waits for the
+// reply on c, for up to timeout in all.  Meanwhile it takes o's output,
+// delivering replies and holding other events for the EventMgr of o's
+// container, so neither o nor a container waiting here is blocked.
+func exchange(o Object, e *Event, c chan *Event, timeout time.Duration) (*Event, error) {
+
+	yin, err := Yin(o)
+	if err != nil {
+		return nil, err
+	}
+	yang, err := Yang(o)
+	if err != nil {
+		return nil, err
+	}
+	var out, held chan *Event
+	if p := o.Ancestor(); p != nil {
+		if comm := p.GetComm(); len(comm) > 0 {
+			out = yang
+			held = comm[0].Yin
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case yin <- e:
+			if c == nil {
+				return nil, nil
+			}
+			yin = nil
+		case r := <-c:
+			return r, nil
+		case r := <-out:
+			if !deliver(r) {
+				registry.hold(held, o, r)
+			}
+		case <-timer.C:
+			return nil, ErrTimeout
+		}
+	}
+}
+
+// Respond answers req by sending a WindEventReply up o's Yang.  rc and
+// n are as for WidgetResult.  Expired requests are not answered.
+func Respond(o Object, req *Event, rc RetCode, n ...interface{}) error {
+
+	if req.Id == 0 {
+		return errors.New("Respond: event is not a request")
+	}
+	if req.Expired() {
+		return ErrTimeout
+	}
+	yang, err := Yang(o)
+	if err != nil {
+		return err
+	}
+
+	r := WidgetResult(rc, n...)
+	r.EventType = WindEventReply
+	r.ReplyTo = req.Id
+	if rc == Fail {
+		for _, x := range n {
+			if v, ok := x.(error); ok {
+				r.Result.Err = v
+			}
+		}
+	}
+	publish(yang, r)
+	yang <- r
+	return nil
+}
+
+// Expired reports whether a request's deadline has passed.
+func (e *Event) Expired() bool {
+	return !e.Deadline.IsZero() && time.Now().After(e.Deadline)
+}
+
+// deliver hands a reply to the Request waiting for it.  It returns
+// false if e is not a reply.  Replies nobody waits for any longer
+// are dropped.
+func deliver(e *Event) bool {
+	if e.ReplyTo == 0 {
+		return false
+	}
+
+	pending.Lock()
+	c, ok := pending.replies[e.ReplyTo]
+	pending.Unlock()
+
+	if ok {
+		select {
+		case c <- e:
+		default:
+		}
+	}
+	return true
+}
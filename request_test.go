@@ -0,0 +1,68 @@
+package windigo
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestRoot returns a managed gadget, and the channel its parent
+// would send on.  Init starts its EventMgr.
+func newTestRoot() (*GadgetType, chan *Event) {
+	root := NewGadget(NewRegion(0, 0, 40, 10), 0, 0)
+	root.SetManaged()
+	down, up := make(chan *Event), make(chan *Event)
+	root.Comm = []IChing{{Yin: down, Yang: up}}
+	go func() {
+		for range up {
+		}
+	}()
+	return root, down
+}
+
+func TestQueryFromHandler(t *testing.T) {
+	root, down := newTestRoot()
+	l, _ := NewLabel(NewRegion(0, 0, 10, 1), "hello", 0, 0)
+	if err := root.Manage(l); err != nil {
+		t.Fatal(err)
+	}
+	root.Init()
+
+	errs := make(chan error, 1)
+	root.Handle(WindEventRestart, func(from Object, e *Event) {
+		_, err := Query(l, time.Second)
+		errs <- err
+	})
+	down <- NewEvent(WindEventRestart)
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Query from an EventHandler did not return")
+	}
+	down <- NewEvent(WindEventExit)
+}
+
+func TestQueryGadget(t *testing.T) {
+	root, down := newTestRoot()
+	g := NewGadget(NewRegion(0, 0, 10, 1), 0, 0)
+	if err := Manage(root, g); err != nil {
+		t.Fatal(err)
+	}
+	root.Init()
+
+	start := time.Now()
+	if _, err := Query(g, time.Second); err != ErrNoReply {
+		t.Fatalf("Query of a gadget: %v, want ErrNoReply", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("Query of a gadget waited")
+	}
+	// g has no EventMgr to accept anything.
+	if err := Send(g, NewEvent(WindEventRestart), 10*time.Millisecond); err != ErrTimeout {
+		t.Fatalf("Send to a hung gadget: %v, want ErrTimeout", err)
+	}
+	down <- NewEvent(WindEventExit)
+}
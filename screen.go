@@ -1,6 +1,11 @@
 package windigo
 
-import termbox "github.com/nsf/termbox-go"
+import (
+	"context"
+	"sync"
+
+	termbox "github.com/nsf/termbox-go"
+)
 
 var screen Screen
 
@@ -9,10 +14,22 @@ var ScreenSizeX, ScreenSizeY int
 
 type Screen struct {
 	WidthHeight
-	windows          []WindowType
+	windows          []*WindowType
 	clickableRegions []ClickableRegion
 	kbdChannel       chan *termbox.Event
 	Comm             IChing
+
+	// Guards kbdChannel.  Keyboard channels replaced by RequestFocus
+	// are closed by the InputEventRouter, their only sender, so it never
+	// sends on a closed channel.
+	focus    sync.Mutex
+	staleKbd []chan *termbox.Event
+	// Widget that holds kbdChannel, if it was taken by ReqFocus.
+	kbdOwner *WidgetType
+
+	// Lifetime of the UI, from Init, and every goroutine Run waits for.
+	ctx context.Context
+	wg  sync.WaitGroup
 }
 
 // Register interest in mouse input events by calling RegClickable
@@ -37,14 +54,53 @@ func (s *Screen) Size() (int, int) {
 	return s.W, s.H
 }
 
+// RequestFocus returns a new keyboard channel.  The previous one is
+// closed the next time a key is pressed.  A widget that took the focus
+// with ReqFocus is told at once that it has lost it.
 func (s *Screen) RequestFocus() (chan *termbox.Event, error) {
+	return s.requestFocus(nil)
+}
+
+// requestFocus is RequestFocus on behalf of w.
+func (s *Screen) requestFocus(w *WidgetType) (chan *termbox.Event, error) {
+	s.focus.Lock()
+	defer s.focus.Unlock()
+
 	if s.kbdChannel != nil {
-		close(s.kbdChannel)
+		s.staleKbd = append(s.staleKbd, s.kbdChannel)
+	}
+	if old := s.kbdOwner; old != nil && old != w {
+		select {
+		case old.blur <- struct{}{}:
+		default:
+		}
 	}
+	s.kbdOwner = w
 	s.kbdChannel = make(chan *termbox.Event)
 	return s.kbdChannel, nil
 }
 
+// focused reports whether c is the current keyboard channel.
+func (s *Screen) focused(c chan *termbox.Event) bool {
+	s.focus.Lock()
+	defer s.focus.Unlock()
+
+	return c != nil && c == s.kbdChannel
+}
+
+// keyboard returns the current keyboard channel, first closing any that
+// have been replaced.
+func (s *Screen) keyboard() chan *termbox.Event {
+	s.focus.Lock()
+	defer s.focus.Unlock()
+
+	for _, c := range s.staleKbd {
+		close(c)
+	}
+	s.staleKbd = nil
+	return s.kbdChannel
+}
+
 func (s *Screen) InputEventRouter() {
 
 	var n int = 0
@@ -58,8 +114,12 @@ mainloop:
 		switch ev := termbox.PollEvent(); ev.Type {
 		// key events follow focus
 		case termbox.EventKey:
-			if s.kbdChannel != nil {
-				s.kbdChannel <- &ev
+			if kbd := s.keyboard(); kbd != nil {
+				select {
+				case kbd <- &ev:
+				case <-s.ctx.Done():
+					break mainloop
+				}
 			}
 		case termbox.EventMouse:
 			for _, r := range s.clickableRegions {
@@ -71,13 +131,21 @@ mainloop:
 						ev.MouseY = ev.MouseY - r.Y
 						// Send the event to the object that owns
 						// this cell.
-						r.C <- &ev
+						select {
+						case r.C <- &ev:
+						case <-s.ctx.Done():
+							break mainloop
+						}
 					}
 				}
 			}
+		case termbox.EventInterrupt:
+			// Run interrupts PollEvent on shutdown.
+			if s.ctx.Err() != nil {
+				break mainloop
+			}
 		case termbox.EventError:
 			panic(ev.Err)
-			break mainloop
 		}
 	}
 
@@ -0,0 +1,331 @@
+package windigo
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// A Glyph is a Sigil that takes more than one row: a W by H block of
+// cells stored row by row.  A Glyph smaller than its widget is repeated
+// to fill it, so a 1x1 Glyph colors the whole widget.  A wide rune takes
+// two cells; the second holds a zero Ch and is not drawn.
+type Glyph struct {
+	W, H  int
+	Cells []Cell
+}
+
+// NewGlyph returns a w by h Glyph with every cell set to c.
+func NewGlyph(w, h int, c Cell) *Glyph {
+	g := &Glyph{W: w, H: h, Cells: make([]Cell, w*h)}
+	for i := range g.Cells {
+		g.Cells[i] = c
+	}
+	return g
+}
+
+// String2Glyph builds a Glyph from s, one row per line.  Short lines are
+// padded with spaces to the width of the longest line.  Tabs are
+// expanded to every eighth column, combining marks are folded into the
+// rune before them as drawText does, and carriage returns and other
+// zero width runes are dropped.
+func String2Glyph(s string, fg, bg Attribute) *Glyph {
+
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	runes := make([][]rune, len(lines))
+
+	glyph := new(Glyph)
+	glyph.H = len(lines)
+	for i, line := range lines {
+		var w int
+		runes[i], w = glyphRunes(line)
+		if w > glyph.W {
+			glyph.W = w
+		}
+	}
+
+	for _, line := range runes {
+		x := 0
+		for _, r := range line {
+			glyph.Cells = append(glyph.Cells, Cell{Ch: r, Fg: fg, Bg: bg})
+			x++
+			if runewidth.RuneWidth(r) == 2 {
+				glyph.Cells = append(glyph.Cells, Cell{Ch: 0, Fg: fg, Bg: bg})
+				x++
+			}
+		}
+		for ; x < glyph.W; x++ {
+			glyph.Cells = append(glyph.Cells, Cell{Ch: ' ', Fg: fg, Bg: bg})
+		}
+	}
+
+	return glyph
+}
+
+// glyphRunes returns the runes of line that take cells, with tabs
+// expanded and combining marks folded in, and the width of the cells
+// they take.
+func glyphRunes(line string) ([]rune, int) {
+	var rs []rune
+	w := 0
+	for _, r := range line {
+		if r == '\t' {
+			for {
+				rs = append(rs, ' ')
+				w++
+				if w%8 == 0 {
+					break
+				}
+			}
+			continue
+		}
+		rw := runewidth.RuneWidth(r)
+		if rw == 0 {
+			if n := len(rs); n > 0 {
+				if c, ok := compose(rs[n-1], r); ok {
+					rs[n-1] = c
+				}
+			}
+			continue
+		}
+		rs = append(rs, r)
+		w += rw
+	}
+	return rs, w
+}
+
+// LoadGlyph reads a text-art Glyph from the file at path.
+func LoadGlyph(path string, fg, bg Attribute) (*Glyph, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return String2Glyph(string(b), fg, bg), nil
+}
+
+// Glyph returns s as a one row Glyph, with a wide rune taking two cells.
+func (s Sigil) Glyph() Glyph {
+	var g Glyph
+	for _, c := range s {
+		g.Cells = append(g.Cells, c)
+		if runewidth.RuneWidth(c.Ch) == 2 {
+			g.Cells = append(g.Cells, Cell{Ch: 0, Fg: c.Fg, Bg: c.Bg})
+		}
+	}
+	if len(g.Cells) > 0 {
+		g.W, g.H = len(g.Cells), 1
+	}
+	return g
+}
+
+// At returns the cell at x, y, repeating the Glyph as if it were tiled.
+func (g Glyph) At(x, y int) Cell {
+	if g.W <= 0 || g.H <= 0 || len(g.Cells) < g.W*g.H {
+		return Cell{Ch: ' '}
+	}
+	return g.Cells[(y%g.H)*g.W+x%g.W]
+}
+
+// Set sets the cell at x, y.  Cells outside the Glyph are ignored.
+func (g *Glyph) Set(x, y int, c Cell) {
+	if x < 0 || y < 0 || x >= g.W || y >= g.H || len(g.Cells) < g.W*g.H {
+		return
+	}
+	g.Cells[y*g.W+x] = c
+}
+
+// drawGlyph draws g once at the top left of o and blanks the rest of o
+// in o's colors.
+func drawGlyph(o Object, g Glyph) {
+	w, h := o.Size()
+	fg, bg := o.Colors()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < g.W && y < g.H && len(g.Cells) >= g.W*g.H {
+				c := g.Cells[y*g.W+x]
+				if c.Ch != 0 {
+					SetCell(o, x, y, c.Ch, c.Fg, c.Bg)
+				}
+				continue
+			}
+			SetCell(o, x, y, ' ', fg, bg)
+		}
+	}
+}
+
+// String returns the characters of the Glyph, one line per row.
+func (g Glyph) String() string {
+	var b strings.Builder
+	for y := 0; y < g.H; y++ {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		for x := 0; x < g.W; x++ {
+			c := g.At(x, y)
+			if c.Ch != 0 {
+				b.WriteRune(c.Ch)
+			}
+		}
+	}
+	return b.String()
+}
+
+// SetGlyph sets the look of state s to g, in place of its Sigil.
+func (fsm *FiniteStateMachine) SetGlyph(s FiniteState, g Glyph) {
+	for len(fsm.Glyphs) < len(fsm.StateFunc) {
+		fsm.Glyphs = append(fsm.Glyphs, Glyph{})
+	}
+	fsm.Glyphs[s] = g
+}
+
+// look returns the Glyph of state s, ignoring animation.
+func (fsm *FiniteStateMachine) look(s FiniteState) Glyph {
+	if int(s) < len(fsm.Glyphs) && fsm.Glyphs[s].Cells != nil {
+		return fsm.Glyphs[s]
+	}
+	if int(s) < len(fsm.Sigil) {
+		return fsm.Sigil[s].Glyph()
+	}
+	return Glyph{}
+}
+
+// An Animation is a sequence of frames shown, in turn, every Interval
+// while its state machine is in the state it belongs to, i.e. for
+// spinners and blinking lamps.  The animation restarts from the first
+// frame each time the state is entered.  Like timed transitions, frames
+// are advanced by the machine's Clock.
+type Animation struct {
+	Frames   []Glyph
+	Interval time.Duration
+}
+
+type fsmFrames struct {
+	sync.Mutex
+	// Frame being shown, and the timer that will advance it.
+	state FiniteState
+	frame int
+	timer Timer
+	gen   uint64
+}
+
+// SetFrames animates state s.  The first frame is shown in place of the
+// state's Sigil, or Glyph, while the animation is not running.
+func (fsm *FiniteStateMachine) SetFrames(s FiniteState, interval time.Duration, frames ...Glyph) {
+	for len(fsm.Animations) < len(fsm.StateFunc) {
+		fsm.Animations = append(fsm.Animations, Animation{})
+	}
+	fsm.Animations[s] = Animation{frames, interval}
+}
+
+func (fsm *FiniteStateMachine) animated(s FiniteState) bool {
+	return int(s) >= 0 && int(s) < len(fsm.Animations) &&
+		len(fsm.Animations[s].Frames) > 1 && fsm.Animations[s].Interval > 0
+}
+
+// Glyph returns the Glyph to draw for the current state, the current
+// frame if the state is animated.
+func (fsm *FiniteStateMachine) Glyph() Glyph {
+	s := fsm.State()
+	if !fsm.validState(s) {
+		return Glyph{}
+	}
+	if int(s) < len(fsm.Animations) && len(fsm.Animations[s].Frames) > 0 {
+		if fsm.animated(s) {
+			fr := fsm.frames()
+			fr.Lock()
+			defer fr.Unlock()
+			if fr.state == s {
+				return fsm.Animations[s].Frames[fr.frame]
+			}
+		}
+		return fsm.Animations[s].Frames[0]
+	}
+	return fsm.look(s)
+}
+
+func (fsm *FiniteStateMachine) frames() *fsmFrames {
+	fsm.mu.Lock()
+	defer fsm.mu.Unlock()
+
+	if fsm.anim == nil {
+		fsm.anim = &fsmFrames{state: FiniteState(-1)}
+	}
+	return fsm.anim
+}
+
+// animate starts the animation of s, if it has one.
+func (fsm *FiniteStateMachine) animate(s FiniteState) {
+	if !fsm.animated(s) {
+		return
+	}
+	fr := fsm.frames()
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if fr.timer != nil {
+		fr.timer.Stop()
+	}
+	fr.gen++
+	fr.state = s
+	fr.frame = 0
+	fsm.nextFrame(fr, fr.gen)
+}
+
+// nextFrame arms the timer for the frame after the current one.  fr is
+// locked.
+func (fsm *FiniteStateMachine) nextFrame(fr *fsmFrames, gen uint64) {
+	a := fsm.Animations[fr.state]
+	fr.timer = fsm.clock().AfterFunc(a.Interval, func() {
+		fr.Lock()
+		if fr.gen != gen {
+			fr.Unlock()
+			return
+		}
+		fr.frame = (fr.frame + 1) % len(a.Frames)
+		fsm.nextFrame(fr, gen)
+		fr.Unlock()
+		if fsm.OnFrame != nil {
+			fsm.OnFrame()
+		}
+	})
+}
+
+// stillAll stops whichever animation is running.
+func (fsm *FiniteStateMachine) stillAll() {
+	fr := fsm.frames()
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if fr.timer != nil {
+		fr.timer.Stop()
+		fr.timer = nil
+	}
+	fr.gen++
+	fr.state = FiniteState(-1)
+}
+
+// still stops the animation of s.
+func (fsm *FiniteStateMachine) still(s FiniteState) {
+	if !fsm.animated(s) {
+		return
+	}
+	fr := fsm.frames()
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if fr.state != s {
+		return
+	}
+	if fr.timer != nil {
+		fr.timer.Stop()
+		fr.timer = nil
+	}
+	fr.gen++
+	fr.state = FiniteState(-1)
+}
@@ -0,0 +1,54 @@
+package windigo
+
+import "testing"
+
+func TestString2Glyph(t *testing.T) {
+	tests := []struct {
+		s    string
+		w, h int
+		rows []string // Each row's runes, a wide rune's second cell as '_'
+	}{
+		{"ab\ncd\n", 2, 2, []string{"ab", "cd"}},
+		{"ab\r\ncd\r\n", 2, 2, []string{"ab", "cd"}},
+		{"a\nbcd", 3, 2, []string{"a  ", "bcd"}},
+		{"\tx\nab\tc", 9, 2, []string{"        x", "ab      c"}},
+		{"e\u0301x\nab", 2, 2, []string{"éx", "ab"}},
+		{"x\u0301y\u200b\nab", 2, 2, []string{"xy", "ab"}},
+		{"日x\nabc", 3, 2, []string{"日_x", "abc"}},
+	}
+	for _, tt := range tests {
+		g := String2Glyph(tt.s, ColorWhite, ColorBlack)
+		if g.W != tt.w || g.H != tt.h || len(g.Cells) != g.W*g.H {
+			t.Errorf("%q: %dx%d with %d cells, want %dx%d", tt.s, g.W, g.H,
+				len(g.Cells), tt.w, tt.h)
+			continue
+		}
+		for y, row := range tt.rows {
+			var got []rune
+			for _, c := range g.Cells[y*g.W : (y+1)*g.W] {
+				if c.Ch == 0 {
+					c.Ch = '_'
+				}
+				got = append(got, c.Ch)
+			}
+			if string(got) != row {
+				t.Errorf("%q row %d: %q, want %q", tt.s, y, string(got), row)
+			}
+		}
+	}
+}
+
+func TestString2Sigil(t *testing.T) {
+	tests := []struct{ s, want string }{
+		{"[x] ok", "[x] ok"},
+		{"cafe\u0301", "café"},
+		{"a\u0302\u0301", "ấ"},
+		{"x\u0301y", "xy"},
+		{"\u0301a", "a"},
+	}
+	for _, tt := range tests {
+		if got := String2Sigil(tt.s, ColorWhite, ColorBlack).String(); got != tt.want {
+			t.Errorf("%q: %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package windigo
+
+import (
+	"math"
+	"sync"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// Characters used to draw Sliders and Spinners.
+const defSliderTrackH rune = 0x2500 // '─' U+2500
+const defSliderTrackV rune = 0x2502 // '│' U+2502
+const defSliderFillH rune = 0x2501  // '━' U+2501
+const defSliderFillV rune = 0x2503  // '┃' U+2503
+const defSliderThumb rune = 0x25CF  // '●' U+25CF
+const defSpinnerDec rune = '-'
+const defSpinnerInc rune = '+'
+
+// The characters to use when drawing Sliders and Spinners, in the
+// order of the SliderChar constants.
+type SliderChar int
+
+const (
+	TrackH SliderChar = iota
+	TrackV
+	FillH
+	FillV
+	Thumb
+	SpinDec
+	SpinInc
+	nSliderChars
+)
+
+var defSliderChars = [nSliderChars]rune{defSliderTrackH, defSliderTrackV,
+	defSliderFillH, defSliderFillV, defSliderThumb, defSpinnerDec,
+	defSpinnerInc}
+
+var SliderChars []rune
+
+func sliderChar(c SliderChar) rune {
+	if int(c) < len(SliderChars) {
+		return SliderChars[c]
+	}
+	return defSliderChars[c]
+}
+
+// Range is the model of a numeric input widget: its value is kept
+// between Min and Max, and if Step is not 0, on a multiple of Step from
+// Min.
+type Range struct {
+	Min, Max, Step float64
+}
+
+// Clamp returns v limited to the range and rounded to a step.
+func (r Range) Clamp(v float64) float64 {
+	if r.Step > 0 {
+		v = r.Min + math.Round((v-r.Min)/r.Step)*r.Step
+	}
+	if v > r.Max {
+		v = r.Max
+	}
+	if v < r.Min {
+		v = r.Min
+	}
+	return v
+}
+
+// step returns the amount an arrow key changes the value by.
+func (r Range) step() float64 {
+	if r.Step > 0 {
+		return r.Step
+	}
+	return (r.Max - r.Min) / 100
+}
+
+// SliderType is a horizontal or vertical slider.  Clicking or dragging
+// on the track moves the thumb there and takes the keyboard focus.  With
+// the focus, the arrow keys move it by a step, PgUp and PgDn by ten and
+// Home and End to the ends.  A vertical slider's minimum is at the
+// bottom.  Whenever the user changes the value the slider sends
+// WindEventValueChange with the new value to its container.  Send it
+// WindEventSetValue to change its value.
+type SliderType struct {
+	WidgetType
+	Range
+	Orientation OrientationType
+
+	mu    sync.Mutex
+	value float64
+}
+
+func NewSlider(r *Region, o OrientationType, rng Range, fg, bg Attribute) (*SliderType, error) {
+
+	s := new(SliderType)
+	s.X = r.X
+	s.Y = r.Y
+	s.W = r.W
+	s.H = r.H
+	s.Fg = fg
+	s.Bg = bg
+	s.kbd = -1
+	s.allowFocus = true
+	s.Range = rng
+	s.Orientation = o
+	s.value = rng.Min
+	s.Fsm = NewEventFSM(s.event)
+	return s, nil
+}
+
+func (s *SliderType) Init() error {
+
+	x, y := s.Loc()
+	w, h := s.Size()
+	p := s.Ancestor()
+
+	r := Region{TopLeft{x, y}, WidthHeight{w, h}, false, false, false}
+	c, err := RegClickable(p, r)
+	if err != nil {
+		return err
+	}
+	s.InputChan = append(s.InputChan, c)
+
+	s.Start()
+
+	return nil
+}
+
+func (s *SliderType) Value() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.value
+}
+
+// SetValue sets the value without telling the container.
+func (s *SliderType) SetValue(v float64) {
+	s.mu.Lock()
+	s.value = s.Clamp(v)
+	s.mu.Unlock()
+
+	if s.Managed() {
+		QueueRedraw(s)
+	}
+}
+
+// length returns the length of the track in cells.
+func (s *SliderType) length() int {
+	w, h := s.Size()
+	if s.Orientation == Vertical {
+		return h
+	}
+	return w
+}
+
+// thumb returns the cell of the track the thumb is in, counting from
+// the minimum end.
+func (s *SliderType) thumb(v float64) int {
+	n := s.length()
+	if n <= 1 || s.Max <= s.Min {
+		return 0
+	}
+	return int(math.Round((v - s.Min) / (s.Max - s.Min) * float64(n-1)))
+}
+
+func (s *SliderType) event(e *Event) *Event {
+
+	switch e.EventType {
+	case WindEventSetValue:
+		if v, ok := e.Payload.(float64); ok {
+			s.SetValue(v)
+		}
+		return WidgetResult(Nop)
+	case WindEventInput:
+	default:
+		return WidgetResult(Nop)
+	}
+
+	s.mu.Lock()
+	before := s.value
+	v := before
+
+	ev := e.Args.Tbox
+	switch ev.Type {
+	case termbox.EventMouse:
+		if ev.Key != termbox.MouseLeft {
+			break
+		}
+		s.ReqFocus()
+		n := s.length()
+		pos := ev.MouseX
+		if s.Orientation == Vertical {
+			pos = n - 1 - ev.MouseY
+		}
+		if n > 1 {
+			v = s.Min + float64(pos)/float64(n-1)*(s.Max-s.Min)
+		}
+	case termbox.EventKey:
+		switch ev.Key {
+		case termbox.KeyArrowRight, termbox.KeyArrowUp:
+			v += s.step()
+		case termbox.KeyArrowLeft, termbox.KeyArrowDown:
+			v -= s.step()
+		case termbox.KeyPgup:
+			v += 10 * s.step()
+		case termbox.KeyPgdn:
+			v -= 10 * s.step()
+		case termbox.KeyHome:
+			v = s.Min
+		case termbox.KeyEnd:
+			v = s.Max
+		}
+	}
+	s.value = s.Clamp(v)
+	v = s.value
+	s.mu.Unlock()
+
+	if v == before {
+		return WidgetResult(Nop)
+	}
+	QueueRedraw(s)
+	return NewEvent(WindEventValueChange, v)
+}
+
+func (s *SliderType) Refresh() {
+
+	if !s.Managed() {
+		return
+	}
+
+	s.mu.Lock()
+	t := s.thumb(s.value)
+	s.mu.Unlock()
+
+	fg, bg := s.Colors()
+	w, h := s.Size()
+	n := s.length()
+
+	track, fill := sliderChar(TrackH), sliderChar(FillH)
+	if s.Orientation == Vertical {
+		track, fill = sliderChar(TrackV), sliderChar(FillV)
+	}
+
+	for i := 0; i < n; i++ {
+		r := track
+		switch {
+		case i == t:
+			r = sliderChar(Thumb)
+		case i < t:
+			r = fill
+		}
+		if s.Orientation == Vertical {
+			for x := 0; x < w; x++ {
+				SetCell(s, x, n-1-i, r, fg, bg)
+			}
+		} else {
+			for y := 0; y < h; y++ {
+				SetCell(s, i, y, r, fg, bg)
+			}
+		}
+	}
+}
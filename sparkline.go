@@ -0,0 +1,164 @@
+package windigo
+
+import (
+	"math"
+	"sync"
+)
+
+// SparklineType is a compact chart of a series of values, one column
+// per value, drawn to an eighth of a cell with the lower block elements
+// '▁' to '█'.  A sparkline taller than a row stacks its blocks.  The
+// values are scaled between Min and Max, or if Max is not more than
+// Min, between the least and greatest values shown.  Once the
+// sparkline is full, each value added scrolls the oldest off the left.
+// NaN values leave a gap.  Send the sparkline WindEventAppend to add a
+// value.
+type SparklineType struct {
+	WidgetType
+	Min, Max float64
+
+	mu   sync.Mutex
+	data []float64
+}
+
+func NewSparkline(r *Region, fg, bg Attribute) (*SparklineType, error) {
+
+	s := new(SparklineType)
+	s.X = r.X
+	s.Y = r.Y
+	s.W = r.W
+	s.H = r.H
+	s.Fg = fg
+	s.Bg = bg
+	s.kbd = -1
+	s.Fsm = NewEventFSM(s.event)
+	return s, nil
+}
+
+func (s *SparklineType) Init() error {
+	s.Start()
+	return nil
+}
+
+func (s *SparklineType) event(e *Event) *Event {
+	if e.EventType == WindEventAppend {
+		if v, ok := e.Payload.(Sample); ok {
+			s.Append(v.Value)
+		}
+	}
+	return WidgetResult(Nop)
+}
+
+// Append adds values to the end of the sparkline and redraws it.
+func (s *SparklineType) Append(v ...float64) {
+	w, _ := s.Size()
+
+	s.mu.Lock()
+	s.data = window(append(s.data, v...), w)
+	s.mu.Unlock()
+
+	if s.Managed() {
+		QueueRedraw(s)
+	}
+}
+
+// SetData replaces the values and redraws the sparkline.
+func (s *SparklineType) SetData(data []float64) {
+	w, _ := s.Size()
+
+	s.mu.Lock()
+	s.data = window(append([]float64(nil), data...), w)
+	s.mu.Unlock()
+
+	if s.Managed() {
+		QueueRedraw(s)
+	}
+}
+
+// Data returns the values shown.
+func (s *SparklineType) Data() []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]float64(nil), s.data...)
+}
+
+// window returns the last n values of data.
+func window(data []float64, n int) []float64 {
+	if n >= 0 && len(data) > n {
+		return append(data[:0], data[len(data)-n:]...)
+	}
+	return data
+}
+
+// dataRange returns min and max if max is more than min, otherwise the
+// least and greatest of the values in data, widened if they are equal.
+func dataRange(min, max float64, data ...[]float64) (float64, float64) {
+	if max > min {
+		return min, max
+	}
+	min, max = math.Inf(1), math.Inf(-1)
+	for _, d := range data {
+		for _, v := range d {
+			if math.IsNaN(v) {
+				continue
+			}
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+	}
+	if math.IsInf(min, 1) {
+		return 0, 1
+	}
+	if max == min {
+		return min - 1, max + 1
+	}
+	return min, max
+}
+
+// column returns the height of v in eighths of a cell in a column h
+// cells high scaled from min to max.
+func column(v, min, max float64, h int) int {
+	if math.IsNaN(v) {
+		return 0
+	}
+	f := (v - min) / (max - min)
+	f = math.Max(0, math.Min(1, f))
+	return int(math.Round(f * float64(8*h)))
+}
+
+func (s *SparklineType) Refresh() {
+
+	if !s.Managed() {
+		return
+	}
+
+	s.mu.Lock()
+	data := append([]float64(nil), s.data...)
+	s.mu.Unlock()
+
+	fg, bg := s.Colors()
+	w, h := s.Size()
+	min, max := dataRange(s.Min, s.Max, data)
+
+	for x := 0; x < w; x++ {
+		eighths := 0
+		if x < len(data) {
+			eighths = column(data[x], min, max, h)
+			// Show the least values rather than nothing.
+			if eighths == 0 && !math.IsNaN(data[x]) {
+				eighths = 1
+			}
+		}
+		for y := 0; y < h; y++ {
+			r := ' '
+			switch n := eighths - 8*(h-1-y); {
+			case n >= 8:
+				r = gaugeFull
+			case n > 0:
+				r = gaugeLower + rune(n)
+			}
+			SetCell(s, x, y, r, fg, bg)
+		}
+	}
+}
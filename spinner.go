@@ -0,0 +1,266 @@
+package windigo
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// SpinnerType is a one row numeric input with a decrement button at its
+// left end and an increment button at its right, and the value, printed
+// with Format, centered between them.  Clicking a button steps the value
+// and takes the keyboard focus.  With the focus, Up and Down (or +)
+// step the value and PgUp and PgDn step it by ten.  Typing a digit, '.'
+// or a leading '-' starts editing the value: Backspace deletes, Enter or
+// losing the focus commits the number typed, clamped to the range, and
+// Esc abandons it.
+// Whenever the user changes the value the spinner sends
+// WindEventValueChange with the new value to its container.  Send it
+// WindEventSetValue to change its value.
+type SpinnerType struct {
+	WidgetType
+	Range
+	Format string
+
+	mu      sync.Mutex
+	value   float64
+	editing bool
+	buf     []rune
+}
+
+func NewSpinner(r *Region, rng Range, fg, bg Attribute) (*SpinnerType, error) {
+
+	s := new(SpinnerType)
+	s.X = r.X
+	s.Y = r.Y
+	s.W = r.W
+	s.H = r.H
+	s.Fg = fg
+	s.Bg = bg
+	s.kbd = -1
+	s.allowFocus = true
+	s.Range = rng
+	s.Format = "%g"
+	s.value = rng.Min
+	s.Fsm = NewEventFSM(s.event)
+	return s, nil
+}
+
+func (s *SpinnerType) Init() error {
+
+	x, y := s.Loc()
+	w, h := s.Size()
+	p := s.Ancestor()
+
+	r := Region{TopLeft{x, y}, WidthHeight{w, h}, false, false, false}
+	c, err := RegClickable(p, r)
+	if err != nil {
+		return err
+	}
+	s.InputChan = append(s.InputChan, c)
+
+	s.Start()
+
+	return nil
+}
+
+func (s *SpinnerType) Value() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.value
+}
+
+// SetValue sets the value, abandoning any edit, without telling the
+// container.
+func (s *SpinnerType) SetValue(v float64) {
+	s.mu.Lock()
+	s.value = s.Clamp(v)
+	s.editing = false
+	s.buf = nil
+	s.mu.Unlock()
+
+	if s.Managed() {
+		QueueRedraw(s)
+	}
+}
+
+// commit ends an edit, setting the value to the number typed if it
+// parses.  s.mu is held.
+func (s *SpinnerType) commit() {
+	if !s.editing {
+		return
+	}
+	if v, err := strconv.ParseFloat(string(s.buf), 64); err == nil {
+		s.value = s.Clamp(v)
+	}
+	s.editing = false
+	s.buf = nil
+}
+
+func (s *SpinnerType) event(e *Event) *Event {
+
+	s.mu.Lock()
+	before := s.value
+	s.mu.Unlock()
+
+	switch e.EventType {
+	case WindEventSetValue:
+		if v, ok := e.Payload.(float64); ok {
+			s.SetValue(v)
+		}
+		return WidgetResult(Nop)
+	case WindEventFocus:
+		if f, ok := e.Payload.(bool); ok && !f {
+			s.mu.Lock()
+			s.commit()
+			s.mu.Unlock()
+		}
+	case WindEventInput:
+		s.input(e.Args.Tbox)
+	default:
+		return WidgetResult(Nop)
+	}
+
+	QueueRedraw(s)
+
+	s.mu.Lock()
+	v := s.value
+	s.mu.Unlock()
+
+	if v == before {
+		return WidgetResult(Nop)
+	}
+	return NewEvent(WindEventValueChange, v)
+}
+
+func (s *SpinnerType) input(ev *termbox.Event) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch ev.Type {
+	case termbox.EventMouse:
+		if ev.Key != termbox.MouseLeft {
+			return
+		}
+		s.ReqFocus()
+		w, _ := s.Size()
+		switch ev.MouseX {
+		case 0:
+			s.commit()
+			s.value = s.Clamp(s.value - s.step())
+		case w - 1:
+			s.commit()
+			s.value = s.Clamp(s.value + s.step())
+		}
+		return
+	case termbox.EventKey:
+	default:
+		return
+	}
+
+	switch ev.Key {
+	case termbox.KeyArrowUp:
+		s.commit()
+		s.value = s.Clamp(s.value + s.step())
+	case termbox.KeyArrowDown:
+		s.commit()
+		s.value = s.Clamp(s.value - s.step())
+	case termbox.KeyPgup:
+		s.commit()
+		s.value = s.Clamp(s.value + 10*s.step())
+	case termbox.KeyPgdn:
+		s.commit()
+		s.value = s.Clamp(s.value - 10*s.step())
+	case termbox.KeyEnter:
+		s.commit()
+	case termbox.KeyEsc:
+		s.editing = false
+		s.buf = nil
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if s.editing && len(s.buf) > 0 {
+			s.buf = s.buf[:len(s.buf)-1]
+		}
+	default:
+		if ev.Key != 0 {
+			return
+		}
+		switch {
+		case ev.Ch >= '0' && ev.Ch <= '9', ev.Ch == '.',
+			ev.Ch == '-' && (!s.editing || len(s.buf) == 0):
+			if !s.editing {
+				s.editing = true
+				s.buf = s.buf[:0]
+			}
+			s.buf = append(s.buf, ev.Ch)
+		case ev.Ch == '+':
+			s.commit()
+			s.value = s.Clamp(s.value + s.step())
+		}
+	}
+}
+
+// Text returns the value as shown, or the number being typed.
+func (s *SpinnerType) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.editing {
+		return string(s.buf)
+	}
+	return fmt.Sprintf(s.Format, s.value)
+}
+
+func (s *SpinnerType) Refresh() {
+
+	if !s.Managed() {
+		return
+	}
+
+	text := s.Text()
+	s.mu.Lock()
+	editing := s.editing
+	s.mu.Unlock()
+
+	fg, bg := s.Colors()
+	w, h := s.Size()
+	if w < 1 || h < 1 {
+		return
+	}
+	for y := 1; y < h; y++ {
+		drawLine(s, y, "", AlignLeft, fg, bg)
+	}
+
+	SetCell(s, 0, 0, sliderChar(SpinDec), fg, bg)
+	if w > 1 {
+		SetCell(s, w-1, 0, sliderChar(SpinInc), fg, bg)
+	}
+	n := w - 2
+	if n <= 0 {
+		return
+	}
+	if editing {
+		// Keep the end being typed in view.
+		text = truncateLeft(text, n)
+	}
+	x := 1 + alignOffset(text, n, AlignCenter)
+	for i := 1; i < x; i++ {
+		SetCell(s, i, 0, ' ', fg, bg)
+	}
+	x = drawText(s, x, 0, n-(x-1), text, fg, bg)
+	for ; x < w-1; x++ {
+		SetCell(s, x, 0, ' ', fg, bg)
+	}
+}
+
+// truncateLeft drops runes from the start of s until it fits in width.
+func truncateLeft(s string, width int) string {
+	r := []rune(s)
+	for len(r) > width {
+		r = r[1:]
+	}
+	return string(r)
+}
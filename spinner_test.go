@@ -0,0 +1,54 @@
+package windigo
+
+import (
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestSpinnerInput(t *testing.T) {
+	tests := []struct {
+		keys  string // Runes typed; '\n' is Enter, '\b' Backspace, '^' Up, 'v' Down, '\x1b' Esc
+		text  string
+		value float64
+	}{
+		{"42\n", "10", 10},
+		{"7\n", "7", 7},
+		{"-5\n", "-5", -5},
+		{"-50\n", "-10", -10},
+		{"5-", "5", 0},
+		{"2.2\n", "2", 2},
+		{"12\b\n", "1", 1},
+		{"^^+", "3", 3},
+		{"v", "-1", -1},
+		{"3^", "4", 4},
+		{"3\x1b", "0", 0},
+		{"-", "-", 0},
+	}
+	for _, tt := range tests {
+		s, _ := NewSpinner(NewRegion(0, 0, 8, 1), Range{-10, 10, 1}, 0, 0)
+		s.SetValue(0)
+		for _, r := range tt.keys {
+			ev := &termbox.Event{Type: termbox.EventKey}
+			switch r {
+			case '\n':
+				ev.Key = termbox.KeyEnter
+			case '\b':
+				ev.Key = termbox.KeyBackspace2
+			case '^':
+				ev.Key = termbox.KeyArrowUp
+			case 'v':
+				ev.Key = termbox.KeyArrowDown
+			case '\x1b':
+				ev.Key = termbox.KeyEsc
+			default:
+				ev.Ch = r
+			}
+			s.input(ev)
+		}
+		if s.Text() != tt.text || s.Value() != tt.value {
+			t.Errorf("typing %q: %q (%g), want %q (%g)", tt.keys, s.Text(),
+				s.Value(), tt.text, tt.value)
+		}
+	}
+}
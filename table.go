@@ -0,0 +1,602 @@
+package windigo
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	runewidth "github.com/mattn/go-runewidth"
+	termbox "github.com/nsf/termbox-go"
+)
+
+// The marks drawn after the title of the column a Table is sorted by.
+var (
+	SortUp   = " ▲"
+	SortDown = " ▼"
+)
+
+// A Column of a Table.  A column with a Width is that many cells wide.
+// The columns without share the width left over in proportion to their
+// Weight, where 0 counts as 1, but are never too narrow for their Title
+// and sort mark.  Less orders the column's cells when the table is
+// sorted by it; if it is nil, numbers come before other cells and are
+// compared as numbers, and other cells as strings.
+type Column struct {
+	Title  string
+	Width  int
+	Weight int
+	Align  Alignment
+	Less   func(a, b string) bool
+}
+
+// TableType shows rows of cells under a header row of column titles,
+// which stays in place as the rows scroll.  Clicking a row selects it
+// and takes the keyboard focus; with the focus Up, Down, PgUp, PgDn,
+// Home and End move the selection and Left and Right scroll the columns
+// sideways if they are wider than the table.  The mouse wheel scrolls
+// the rows.  Clicking a column's title sorts the rows by it, and
+// clicking it again reverses the order.  When the user selects a row the
+// table sends WindEventSelectionChange, with the row's index and first
+// cell, to its container, and when they press Enter on it
+// WindEventActivate with its index.  Rows keep their index however the
+// table is sorted, and may be added, replaced and deleted one at a time.
+type TableType struct {
+	WidgetType
+	Columns []Column
+
+	mu       sync.Mutex
+	rows     [][]string
+	order    []int
+	sortCol  int
+	sortDesc bool
+	selected int
+	top      int
+	left     int
+}
+
+func NewTable(r *Region, columns []Column, fg, bg Attribute) (*TableType, error) {
+
+	if len(columns) == 0 {
+		return nil, errors.New("NewTable: no columns")
+	}
+
+	t := new(TableType)
+	t.X = r.X
+	t.Y = r.Y
+	t.W = r.W
+	t.H = r.H
+	t.Fg = fg
+	t.Bg = bg
+	t.kbd = -1
+	t.allowFocus = true
+	t.Columns = append([]Column(nil), columns...)
+	t.sortCol = -1
+	t.selected = -1
+	t.Fsm = NewEventFSM(t.event)
+	return t, nil
+}
+
+func (t *TableType) Init() error {
+
+	x, y := t.Loc()
+	w, h := t.Size()
+	p := t.Ancestor()
+
+	r := Region{TopLeft{x, y}, WidthHeight{w, h}, false, false, false}
+	c, err := RegClickable(p, r)
+	if err != nil {
+		return err
+	}
+	t.InputChan = append(t.InputChan, c)
+
+	t.Start()
+
+	return nil
+}
+
+// redraw redraws the table if it is managed.
+func (t *TableType) redraw() {
+	if t.Managed() {
+		QueueRedraw(t)
+	}
+}
+
+// SetRows replaces the rows and clears the selection.
+func (t *TableType) SetRows(rows [][]string) {
+	t.mu.Lock()
+	t.rows = make([][]string, len(rows))
+	for i, row := range rows {
+		t.rows[i] = append([]string(nil), row...)
+	}
+	t.selected = -1
+	t.top = 0
+	t.sort()
+	t.mu.Unlock()
+
+	t.redraw()
+}
+
+// AppendRow adds a row to the table and returns its index.
+func (t *TableType) AppendRow(row []string) int {
+	t.mu.Lock()
+	t.rows = append(t.rows, append([]string(nil), row...))
+	i := len(t.rows) - 1
+	t.order = append(t.order, i)
+	t.sort()
+	t.mu.Unlock()
+
+	t.redraw()
+	return i
+}
+
+// SetRow replaces row i.
+func (t *TableType) SetRow(i int, row []string) error {
+	t.mu.Lock()
+	if i < 0 || i >= len(t.rows) {
+		t.mu.Unlock()
+		return errors.New("SetRow: no such row")
+	}
+	t.rows[i] = append([]string(nil), row...)
+	t.sort()
+	t.mu.Unlock()
+
+	t.redraw()
+	return nil
+}
+
+// DeleteRow deletes row i.  The rows after it move up an index.
+func (t *TableType) DeleteRow(i int) error {
+	t.mu.Lock()
+	if i < 0 || i >= len(t.rows) {
+		t.mu.Unlock()
+		return errors.New("DeleteRow: no such row")
+	}
+	t.rows = append(t.rows[:i], t.rows[i+1:]...)
+	order := t.order[:0]
+	for _, j := range t.order {
+		switch {
+		case j < i:
+			order = append(order, j)
+		case j > i:
+			order = append(order, j-1)
+		}
+	}
+	t.order = order
+	switch {
+	case t.selected == i:
+		t.selected = -1
+	case t.selected > i:
+		t.selected--
+	}
+	t.mu.Unlock()
+
+	t.redraw()
+	return nil
+}
+
+// Row returns a copy of row i, or nil if there is no such row.
+func (t *TableType) Row(i int) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if i < 0 || i >= len(t.rows) {
+		return nil
+	}
+	return append([]string(nil), t.rows[i]...)
+}
+
+// Len returns the number of rows.
+func (t *TableType) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.rows)
+}
+
+// Order returns the indexes of the rows in the order they are shown.
+func (t *TableType) Order() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]int(nil), t.order...)
+}
+
+// Selected returns the index of the selected row, or -1 if none is.
+func (t *TableType) Selected() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.selected
+}
+
+// SetSelected selects row i (-1 for none), scrolling it into view,
+// without telling the container.
+func (t *TableType) SetSelected(i int) {
+	t.mu.Lock()
+	if i < -1 || i >= len(t.rows) {
+		i = -1
+	}
+	t.selected = i
+	t.show()
+	t.mu.Unlock()
+
+	t.redraw()
+}
+
+// SortBy sorts the rows by column col, in descending order if desc is
+// set.  A col of -1 restores the order the rows were added in.
+func (t *TableType) SortBy(col int, desc bool) {
+	t.mu.Lock()
+	if col < -1 || col >= len(t.Columns) {
+		col = -1
+	}
+	t.sortCol = col
+	t.sortDesc = desc
+	t.sort()
+	t.show()
+	t.mu.Unlock()
+
+	t.redraw()
+}
+
+// sort rebuilds the display order.  t.mu is held.
+func (t *TableType) sort() {
+	if len(t.order) != len(t.rows) {
+		t.order = make([]int, len(t.rows))
+		for i := range t.order {
+			t.order[i] = i
+		}
+	}
+	if t.sortCol < 0 {
+		sort.Ints(t.order)
+		return
+	}
+
+	col := t.sortCol
+	less := t.Columns[col].Less
+	if less == nil {
+		less = lessCell
+	}
+	cell := func(i int) string {
+		if col < len(t.rows[i]) {
+			return t.rows[i][col]
+		}
+		return ""
+	}
+	sort.SliceStable(t.order, func(a, b int) bool {
+		x, y := cell(t.order[a]), cell(t.order[b])
+		if t.sortDesc {
+			return less(y, x)
+		}
+		return less(x, y)
+	})
+}
+
+// lessCell orders numbers before other cells.  Numbers are compared as
+// numbers and other cells, NaN among them, as strings.
+func lessCell(a, b string) bool {
+	x, xnum := cellNumber(a)
+	y, ynum := cellNumber(b)
+	switch {
+	case xnum && ynum:
+		return x < y
+	case xnum != ynum:
+		return xnum
+	}
+	return a < b
+}
+
+// cellNumber returns the number in s, and whether there is one.
+func cellNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v, err == nil && !math.IsNaN(v)
+}
+
+// widths returns the width of each column.
+func (t *TableType) widths() []int {
+	w, _ := t.Size()
+	widths := make([]int, len(t.Columns))
+
+	rest := w - (len(t.Columns) - 1)
+	weights := 0
+	for i, c := range t.Columns {
+		if c.Width > 0 {
+			widths[i] = c.Width
+			rest -= c.Width
+			continue
+		}
+		weight := c.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		weights += weight
+	}
+	for i, c := range t.Columns {
+		if c.Width > 0 {
+			continue
+		}
+		weight := c.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		share := 0
+		if rest > 0 {
+			share = rest * weight / weights
+			rest -= share
+		}
+		weights -= weight
+		if min := runewidth.StringWidth(c.Title + SortUp); share < min {
+			share = min
+		}
+		widths[i] = share
+	}
+	return widths
+}
+
+// rowsShown returns the number of rows there is room for.  t.mu is held.
+func (t *TableType) rowsShown() int {
+	_, h := t.Size()
+	return h - 1
+}
+
+// show scrolls the selected row into view.  t.mu is held.
+func (t *TableType) show() {
+	n := t.rowsShown()
+	if t.selected >= 0 {
+		for p, i := range t.order {
+			if i != t.selected {
+				continue
+			}
+			if p < t.top {
+				t.top = p
+			}
+			if p >= t.top+n {
+				t.top = p - n + 1
+			}
+			break
+		}
+	}
+	if t.top > len(t.order)-n {
+		t.top = len(t.order) - n
+	}
+	if t.top < 0 {
+		t.top = 0
+	}
+}
+
+// position returns where row i is shown in the order, or -1.  t.mu is
+// held.
+func (t *TableType) position(i int) int {
+	for p, j := range t.order {
+		if j == i {
+			return p
+		}
+	}
+	return -1
+}
+
+func (t *TableType) event(e *Event) *Event {
+	if e.EventType != WindEventInput {
+		return WidgetResult(Nop)
+	}
+
+	ev := e.Args.Tbox
+	widths := t.widths()
+
+	t.mu.Lock()
+	before := t.selected
+	p := t.position(t.selected)
+	n := t.rowsShown()
+	activate := false
+
+	switch ev.Type {
+	case termbox.EventMouse:
+		switch ev.Key {
+		case termbox.MouseWheelUp:
+			t.top -= 3
+		case termbox.MouseWheelDown:
+			t.top += 3
+		case termbox.MouseLeft:
+			t.ReqFocus()
+			if ev.MouseY == 0 {
+				col := columnAt(widths, ev.MouseX+t.left)
+				if col >= 0 {
+					if col == t.sortCol {
+						t.sortDesc = !t.sortDesc
+					} else {
+						t.sortCol, t.sortDesc = col, false
+					}
+					t.sort()
+					t.show()
+				}
+			} else if q := t.top + ev.MouseY - 1; q < len(t.order) {
+				t.selected = t.order[q]
+			}
+		}
+		if t.top > len(t.order)-n {
+			t.top = len(t.order) - n
+		}
+		if t.top < 0 {
+			t.top = 0
+		}
+	case termbox.EventKey:
+		moved := true
+		switch ev.Key {
+		case termbox.KeyArrowUp:
+			p--
+		case termbox.KeyArrowDown:
+			p++
+		case termbox.KeyPgup:
+			p -= n
+		case termbox.KeyPgdn:
+			p += n
+		case termbox.KeyHome:
+			p = 0
+		case termbox.KeyEnd:
+			p = len(t.order) - 1
+		case termbox.KeyArrowLeft:
+			t.left -= 4
+			moved = false
+		case termbox.KeyArrowRight:
+			t.left += 4
+			moved = false
+		case termbox.KeyEnter:
+			activate = t.selected >= 0
+			moved = false
+		default:
+			moved = false
+		}
+		if moved && len(t.order) > 0 {
+			if p >= len(t.order) {
+				p = len(t.order) - 1
+			}
+			if p < 0 {
+				p = 0
+			}
+			t.selected = t.order[p]
+		}
+		total := len(widths) - 1
+		for _, w := range widths {
+			total += w
+		}
+		w, _ := t.Size()
+		if t.left > total-w {
+			t.left = total - w
+		}
+		if t.left < 0 {
+			t.left = 0
+		}
+		t.show()
+	}
+	sel := t.selected
+	var label string
+	if sel >= 0 && len(t.rows[sel]) > 0 {
+		label = t.rows[sel][0]
+	}
+	t.mu.Unlock()
+
+	QueueRedraw(t)
+
+	switch {
+	case activate:
+		return NewEvent(WindEventActivate, sel)
+	case sel != before:
+		return NewEvent(WindEventSelectionChange, Selection{sel, label})
+	}
+	return WidgetResult(Nop)
+}
+
+// columnAt returns the column at x, or -1 if x is on a separator or
+// beyond the last column.
+func columnAt(widths []int, x int) int {
+	for i, w := range widths {
+		if x < w {
+			return i
+		}
+		x -= w + 1
+		if x < 0 {
+			return -1
+		}
+	}
+	return -1
+}
+
+// tableLine lays out the cells of a row of a table as a line of runes,
+// a wide rune followed by a 0.
+func tableLine(columns []Column, widths []int, cells []string) []rune {
+	var line []rune
+	for i, w := range widths {
+		if i > 0 {
+			line = append(line, OutlineChars[VB])
+		}
+		s := ""
+		if i < len(cells) {
+			s = cells[i]
+		}
+		s = truncate(s, w, runewidth.StringWidth(s) > w)
+		pad := alignOffset(s, w, columns[i].Align)
+		n := 0
+		for ; n < pad; n++ {
+			line = append(line, ' ')
+		}
+		for _, r := range s {
+			switch runewidth.RuneWidth(r) {
+			case 0:
+				continue
+			case 2:
+				line = append(line, r, 0)
+				n += 2
+				continue
+			}
+			line = append(line, r)
+			n++
+		}
+		for ; n < w; n++ {
+			line = append(line, ' ')
+		}
+	}
+	return line
+}
+
+// drawTableLine draws the part of line from column left across row y.
+func (t *TableType) drawTableLine(y int, line []rune, left int, fg, bg Attribute) {
+	w, _ := t.Size()
+	for x := 0; x < w; x++ {
+		r := ' '
+		if i := left + x; i < len(line) {
+			r = line[i]
+		}
+		if r == 0 {
+			if x > 0 {
+				continue
+			}
+			// The second half of a wide rune.
+			r = ' '
+		}
+		SetCell(t, x, y, r, fg, bg)
+	}
+}
+
+func (t *TableType) Refresh() {
+
+	if !t.Managed() {
+		return
+	}
+
+	fg, bg := t.Colors()
+	widths := t.widths()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	titles := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		titles[i] = c.Title
+		if i == t.sortCol {
+			if t.sortDesc {
+				titles[i] += SortDown
+			} else {
+				titles[i] += SortUp
+			}
+		}
+	}
+	t.drawTableLine(0, tableLine(t.Columns, widths, titles), t.left, fg|AttrBold, bg)
+
+	_, h := t.Size()
+	for y := 1; y < h; y++ {
+		p := t.top + y - 1
+		if p >= len(t.order) {
+			t.drawTableLine(y, nil, 0, fg, bg)
+			continue
+		}
+		i := t.order[p]
+		f, b := fg, bg
+		if i == t.selected {
+			f |= AttrReverse
+		}
+		t.drawTableLine(y, tableLine(t.Columns, widths, t.rows[i]), t.left, f, b)
+	}
+}
@@ -0,0 +1,40 @@
+package windigo
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLessCell(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"9", "10", true},
+		{"10", "9", false},
+		{"-1", "0.5", true},
+		{" 3", "4 ", true},
+		{"1e3", "999", false},
+		{"10", "abc", true},
+		{"abc", "10", false},
+		{"", "0", false},
+		{"NaN", "1", false},
+		{"1", "NaN", true},
+		{"NaN", "abc", true},
+		{"abc", "abd", true},
+		{"5", "5", false},
+	}
+	for _, tt := range tests {
+		if got := lessCell(tt.a, tt.b); got != tt.want {
+			t.Errorf("lessCell(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	cells := []string{"abc", "10", "NaN", "", "9", "-1", "b", "2.5"}
+	sort.SliceStable(cells, func(i, j int) bool { return lessCell(cells[i], cells[j]) })
+	want := []string{"-1", "2.5", "9", "10", "", "NaN", "abc", "b"}
+	if !reflect.DeepEqual(cells, want) {
+		t.Errorf("sorted %q, want %q", cells, want)
+	}
+}
@@ -0,0 +1,163 @@
+package windigo
+
+import (
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// Text layout shared by the widgets that draw text.  Widths are in
+// terminal cells, as measured by runewidth, so wide runes take two.
+
+type Alignment int
+
+const (
+	AlignLeft Alignment = iota
+	AlignCenter
+	AlignRight
+)
+
+type WrapMode int
+
+const (
+	// Only newlines start a new line.
+	WrapNone WrapMode = iota
+	// Lines are broken at the width, mid word if need be.
+	WrapChar
+	// Lines are broken between words.  Words wider than the width are
+	// broken as for WrapChar.
+	WrapWord
+)
+
+// The rune marking truncated text.
+var Ellipsis = '…'
+
+// wrapText splits s into lines no wider than width, except that with
+// WrapNone lines are only split at newlines.
+func wrapText(s string, width int, mode WrapMode) []string {
+	var lines []string
+
+	for _, para := range strings.Split(s, "\n") {
+		switch {
+		case mode == WrapNone || width <= 0:
+			lines = append(lines, para)
+		case mode == WrapChar:
+			lines = append(lines, wrapChars(para, width)...)
+		default:
+			lines = append(lines, wrapWords(para, width)...)
+		}
+	}
+	return lines
+}
+
+func wrapChars(s string, width int) []string {
+	var lines []string
+	var line []rune
+	w := 0
+
+	for _, r := range s {
+		rw := runewidth.RuneWidth(r)
+		if w+rw > width && len(line) > 0 {
+			lines = append(lines, string(line))
+			line = line[:0]
+			w = 0
+		}
+		line = append(line, r)
+		w += rw
+	}
+	return append(lines, string(line))
+}
+
+func wrapWords(s string, width int) []string {
+	var lines []string
+	line := ""
+
+	for _, word := range strings.Fields(s) {
+		switch {
+		case line == "":
+			line = word
+		case runewidth.StringWidth(line)+1+runewidth.StringWidth(word) <= width:
+			line += " " + word
+			continue
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+		if runewidth.StringWidth(line) > width {
+			broken := wrapChars(line, width)
+			lines = append(lines, broken[:len(broken)-1]...)
+			line = broken[len(broken)-1]
+		}
+	}
+	return append(lines, line)
+}
+
+// truncate cuts s to width cells, ending it with Ellipsis if ellipsis is
+// set and anything was cut.
+func truncate(s string, width int, ellipsis bool) string {
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	if ellipsis {
+		ew := runewidth.RuneWidth(Ellipsis)
+		return runewidth.Truncate(s, width-ew, "") + string(Ellipsis)
+	}
+	return runewidth.Truncate(s, width, "")
+}
+
+// alignOffset returns the column at which s starts when aligned in width.
+func alignOffset(s string, width int, align Alignment) int {
+	pad := width - runewidth.StringWidth(s)
+	if pad <= 0 {
+		return 0
+	}
+	switch align {
+	case AlignCenter:
+		return pad / 2
+	case AlignRight:
+		return pad
+	}
+	return 0
+}
+
+// drawText draws s on row y of o from column x, clipped to width cells.
+// A combining mark is folded into the rune before it where Unicode has
+// a precomposed rune for the pair; other zero width runes are dropped.
+// It returns the column after the last cell drawn.
+func drawText(o Object, x, y, width int, s string, fg, bg Attribute) int {
+	end := x + width
+	last, lastx := rune(0), 0
+	for _, r := range s {
+		rw := runewidth.RuneWidth(r)
+		if rw == 0 {
+			if c, ok := compose(last, r); ok {
+				SetCell(o, lastx, y, c, fg, bg)
+				last = c
+			}
+			continue
+		}
+		if x+rw > end {
+			break
+		}
+		SetCell(o, x, y, r, fg, bg)
+		last, lastx = r, x
+		x += rw
+	}
+	return x
+}
+
+// drawLine fills row y of o with s, aligned in the width of o.
+func drawLine(o Object, y int, s string, align Alignment, fg, bg Attribute) {
+	w, _ := o.Size()
+	x := alignOffset(s, w, align)
+	for i := 0; i < x; i++ {
+		SetCell(o, i, y, ' ', fg, bg)
+	}
+	x = drawText(o, x, y, w-x, s, fg, bg)
+	for ; x < w; x++ {
+		SetCell(o, x, y, ' ', fg, bg)
+	}
+}
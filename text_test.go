@@ -0,0 +1,52 @@
+package windigo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		mode  WrapMode
+		want  []string
+	}{
+		{"the quick brown fox", 10, WrapWord, []string{"the quick", "brown fox"}},
+		{"the quick brown fox", 10, WrapChar, []string{"the quick ", "brown fox"}},
+		{"the quick brown fox", 10, WrapNone, []string{"the quick brown fox"}},
+		{"a\nb c", 10, WrapWord, []string{"a", "b c"}},
+		{"abcdefgh ij", 3, WrapWord, []string{"abc", "def", "gh", "ij"}},
+		{"日本語", 4, WrapChar, []string{"日本", "語"}},
+		{"", 5, WrapWord, []string{""}},
+		{"abc", 0, WrapWord, []string{"abc"}},
+	}
+	for _, tt := range tests {
+		if got := wrapText(tt.s, tt.width, tt.mode); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapText(%q, %d, %d) = %q, want %q", tt.s, tt.width,
+				tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s        string
+		width    int
+		ellipsis bool
+		want     string
+	}{
+		{"hello", 5, true, "hello"},
+		{"hello", 4, false, "hell"},
+		{"hello", 4, true, "hel…"},
+		{"hello", 0, true, ""},
+		{"日本語", 5, false, "日本"},
+		{"日本語", 4, true, "日…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.width, tt.ellipsis); got != tt.want {
+			t.Errorf("truncate(%q, %d, %v) = %q, want %q", tt.s, tt.width,
+				tt.ellipsis, got, tt.want)
+		}
+	}
+}
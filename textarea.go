@@ -0,0 +1,651 @@
+package windigo
+
+import (
+	"strings"
+	"sync"
+
+	runewidth "github.com/mattn/go-runewidth"
+	termbox "github.com/nsf/termbox-go"
+)
+
+// TextAreaType is a multi-line text editor widget.  Like TextInput it
+// takes the keyboard focus when clicked and shows the terminal cursor
+// while it has it.  With Wrap set to WrapNone long lines scroll
+// horizontally, otherwise they are wrapped across rows.  The view
+// scrolls vertically to keep the cursor in sight.
+//
+// Keys:
+//
+//	arrows, Home, End, PgUp, PgDn   move the cursor
+//	Backspace, Delete               delete a rune or the selection
+//	Enter, Tab                      insert a newline, spaces
+//	Ctrl-Space                      set the mark, selecting from it
+//	Esc                             clear the mark
+//	Ctrl-C, Ctrl-X, Ctrl-V          copy, cut, paste
+//	Ctrl-Z, Ctrl-Y                  undo, redo
+//
+// Dragging with the mouse also selects.  Every edit sends
+// WindEventTextChange, with the whole text, to the container.
+type TextAreaType struct {
+	WidgetType
+
+	Wrap WrapMode
+	// Spaces inserted by Tab.
+	TabWidth int
+	// How many edits can be undone.
+	UndoLen int
+
+	mu    sync.Mutex
+	lines [][]rune
+	// Cursor, as a line and a rune index into it, and the cell column
+	// vertical movement aims for.
+	row, col int
+	goal     int
+	// Selection from the mark to the cursor.
+	mark             bool
+	markRow, markCol int
+	// First row and (with WrapNone) cell column in view.
+	top, left int
+	focused   bool
+	clipboard string
+	undo      []textSnapshot
+	redo      []textSnapshot
+	lastEdit  editKind
+}
+
+type textSnapshot struct {
+	text     string
+	row, col int
+}
+
+// Consecutive edits of the same kind are undone together.
+type editKind int
+
+const (
+	editNone editKind = iota
+	editInsert
+	editDelete
+	editOther
+)
+
+// A row of the view: the runes [start, end) of a line.
+type textRow struct {
+	line, start, end int
+}
+
+func NewTextArea(r *Region, fg, bg Attribute) (*TextAreaType, error) {
+
+	a := new(TextAreaType)
+	a.X = r.X
+	a.Y = r.Y
+	a.W = r.W
+	a.H = r.H
+	a.Fg = fg
+	a.Bg = bg
+	a.kbd = -1
+	a.allowFocus = true
+	a.TabWidth = 4
+	a.UndoLen = 100
+	a.lines = [][]rune{nil}
+	a.goal = -1
+	a.Fsm = NewEventFSM(a.event)
+	return a, nil
+}
+
+func (a *TextAreaType) Init() error {
+
+	x, y := a.Loc()
+	w, h := a.Size()
+	p := a.Ancestor()
+
+	r := Region{TopLeft{x, y}, WidthHeight{w, h}, false, false, false}
+	c, err := RegClickable(p, r)
+	if err != nil {
+		return err
+	}
+	a.InputChan = append(a.InputChan, c)
+
+	a.Start()
+
+	return nil
+}
+
+// Text returns the text being edited, lines separated by newlines.
+func (a *TextAreaType) Text() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.text()
+}
+
+// SetText replaces the text, moves the cursor to its start and forgets
+// the undo history.
+func (a *TextAreaType) SetText(s string) {
+	a.mu.Lock()
+	a.setText(s)
+	a.row, a.col = 0, 0
+	a.mark = false
+	a.undo, a.redo = nil, nil
+	a.lastEdit = editNone
+	a.fixView()
+	a.mu.Unlock()
+
+	if a.Managed() {
+		QueueRedraw(a)
+	}
+}
+
+// Selection returns the selected text.
+func (a *TextAreaType) Selection() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.selected()
+}
+
+func (a *TextAreaType) text() string {
+	s := make([]string, len(a.lines))
+	for i, l := range a.lines {
+		s[i] = string(l)
+	}
+	return strings.Join(s, "\n")
+}
+
+func (a *TextAreaType) setText(s string) {
+	a.lines = nil
+	for _, l := range strings.Split(s, "\n") {
+		a.lines = append(a.lines, []rune(l))
+	}
+}
+
+// selection returns the selection's start and end, in order.
+func (a *TextAreaType) selection() (sr, sc, er, ec int, ok bool) {
+	if !a.mark || (a.markRow == a.row && a.markCol == a.col) {
+		return 0, 0, 0, 0, false
+	}
+	sr, sc, er, ec = a.markRow, a.markCol, a.row, a.col
+	if er < sr || (er == sr && ec < sc) {
+		sr, sc, er, ec = er, ec, sr, sc
+	}
+	return sr, sc, er, ec, true
+}
+
+func (a *TextAreaType) selected() string {
+	sr, sc, er, ec, ok := a.selection()
+	if !ok {
+		return ""
+	}
+	if sr == er {
+		return string(a.lines[sr][sc:ec])
+	}
+	s := []string{string(a.lines[sr][sc:])}
+	for i := sr + 1; i < er; i++ {
+		s = append(s, string(a.lines[i]))
+	}
+	s = append(s, string(a.lines[er][:ec]))
+	return strings.Join(s, "\n")
+}
+
+// deleteSelection removes the selected text, leaving the cursor where
+// it started.
+func (a *TextAreaType) deleteSelection() bool {
+	sr, sc, er, ec, ok := a.selection()
+	if !ok {
+		return false
+	}
+	tail := append([]rune(nil), a.lines[er][ec:]...)
+	a.lines[sr] = append(a.lines[sr][:sc], tail...)
+	a.lines = append(a.lines[:sr+1], a.lines[er+1:]...)
+	a.row, a.col = sr, sc
+	a.mark = false
+	return true
+}
+
+// insert puts s at the cursor, replacing any selection.
+func (a *TextAreaType) insert(s string) {
+	a.deleteSelection()
+
+	parts := strings.Split(s, "\n")
+	line := a.lines[a.row]
+	tail := append([]rune(nil), line[a.col:]...)
+	head := append(line[:a.col:a.col], []rune(parts[0])...)
+
+	if len(parts) == 1 {
+		a.lines[a.row] = append(head, tail...)
+		a.col = len(head)
+		return
+	}
+
+	added := [][]rune{head}
+	for _, p := range parts[1 : len(parts)-1] {
+		added = append(added, []rune(p))
+	}
+	last := []rune(parts[len(parts)-1])
+	a.col = len(last)
+	added = append(added, append(last, tail...))
+
+	lines := append([][]rune(nil), a.lines[:a.row]...)
+	lines = append(lines, added...)
+	lines = append(lines, a.lines[a.row+1:]...)
+	a.lines = lines
+	a.row += len(added) - 1
+}
+
+// snapshot saves the text for undo before an edit of kind k.
+func (a *TextAreaType) snapshot(k editKind) {
+	if k != editOther && k == a.lastEdit {
+		return
+	}
+	a.lastEdit = k
+	a.undo = append(a.undo, textSnapshot{a.text(), a.row, a.col})
+	if a.UndoLen > 0 && len(a.undo) > a.UndoLen {
+		a.undo = a.undo[len(a.undo)-a.UndoLen:]
+	}
+	a.redo = nil
+}
+
+func (a *TextAreaType) restore(from, to *[]textSnapshot) {
+	if len(*from) == 0 {
+		return
+	}
+	s := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, textSnapshot{a.text(), a.row, a.col})
+	a.setText(s.text)
+	a.row, a.col = s.row, s.col
+	a.mark = false
+	a.lastEdit = editNone
+}
+
+// rows lays the text out in rows of the view.
+func (a *TextAreaType) rows() []textRow {
+	w, _ := a.Size()
+	var rows []textRow
+	for i, l := range a.lines {
+		starts := breakLine(l, w, a.Wrap)
+		for j, s := range starts {
+			end := len(l)
+			if j+1 < len(starts) {
+				end = starts[j+1]
+			}
+			rows = append(rows, textRow{i, s, end})
+		}
+	}
+	return rows
+}
+
+// breakLine returns the index of the first rune of each row line is
+// wrapped into.
+func breakLine(line []rune, width int, mode WrapMode) []int {
+	starts := []int{0}
+	if mode == WrapNone || width <= 0 {
+		return starts
+	}
+
+	start, w, space := 0, 0, -1
+	for i, r := range line {
+		rw := runewidth.RuneWidth(r)
+		if w+rw > width && i > start {
+			b := i
+			if mode == WrapWord && space >= start {
+				b = space + 1
+			}
+			starts = append(starts, b)
+			start = b
+			w = runewidth.StringWidth(string(line[b:i]))
+			space = -1
+		}
+		if r == ' ' {
+			space = i
+		}
+		w += rw
+	}
+	return starts
+}
+
+// cursorRow returns the row of the view holding the cursor.
+func (a *TextAreaType) cursorRow(rows []textRow) int {
+	for i, r := range rows {
+		if r.line != a.row || a.col < r.start {
+			continue
+		}
+		last := i+1 == len(rows) || rows[i+1].line != r.line
+		if a.col < r.end || last {
+			return i
+		}
+	}
+	return 0
+}
+
+// colAt returns the rune index in row r at cell column x.
+func (a *TextAreaType) colAt(rows []textRow, i, x int) int {
+	r := rows[i]
+	line := a.lines[r.line]
+	cells := 0
+	for c := r.start; c < r.end; c++ {
+		cells += runewidth.RuneWidth(line[c])
+		if cells > x {
+			return c
+		}
+	}
+	last := i+1 == len(rows) || rows[i+1].line != r.line
+	if !last && r.end > r.start {
+		return r.end - 1
+	}
+	return r.end
+}
+
+// cursorCell returns the cursor's cell column within its row.
+func (a *TextAreaType) cursorCell(r textRow) int {
+	return runewidth.StringWidth(string(a.lines[a.row][r.start:a.col]))
+}
+
+// fixView scrolls to keep the cursor in view.
+func (a *TextAreaType) fixView() {
+	w, h := a.Size()
+	rows := a.rows()
+	i := a.cursorRow(rows)
+	if i < a.top {
+		a.top = i
+	}
+	if i >= a.top+h {
+		a.top = i - h + 1
+	}
+	if a.Wrap != WrapNone {
+		a.left = 0
+		return
+	}
+	x := a.cursorCell(rows[i])
+	if x < a.left {
+		a.left = x
+	}
+	if x >= a.left+w {
+		a.left = x - w + 1
+	}
+}
+
+// moveRows moves the cursor n rows of the view up (n < 0) or down.
+func (a *TextAreaType) moveRows(n int) {
+	rows := a.rows()
+	i := a.cursorRow(rows)
+	if a.goal < 0 {
+		a.goal = a.cursorCell(rows[i])
+	}
+	i += n
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(rows) {
+		i = len(rows) - 1
+	}
+	a.row = rows[i].line
+	a.col = a.colAt(rows, i, a.goal)
+}
+
+func (a *TextAreaType) event(e *Event) *Event {
+
+	switch e.EventType {
+	case WindEventInput:
+		ev := e.Args.Tbox
+		switch ev.Type {
+		case termbox.EventKey:
+			return a.key(ev)
+		case termbox.EventMouse:
+			a.mouse(ev)
+		}
+	case WindEventFocus:
+		on, _ := e.Payload.(bool)
+		a.mu.Lock()
+		a.focused = on
+		a.mu.Unlock()
+		QueueRedraw(a)
+	case WindEventSetText:
+		s, _ := e.Payload.(string)
+		a.SetText(s)
+	}
+	return WidgetResult(Nop)
+}
+
+func (a *TextAreaType) mouse(ev *termbox.Event) {
+	switch ev.Key {
+	case termbox.MouseLeft:
+		a.ReqFocus()
+	case termbox.MouseRelease:
+	default:
+		return
+	}
+
+	a.mu.Lock()
+	a.focused = a.HaveFocus()
+	rows := a.rows()
+	i := a.top + ev.MouseY
+	if i >= len(rows) {
+		i = len(rows) - 1
+	}
+	x := ev.MouseX
+	if a.Wrap == WrapNone {
+		x += a.left
+	}
+	row, col := rows[i].line, a.colAt(rows, i, x)
+
+	switch {
+	case ev.Key == termbox.MouseRelease:
+		if a.mark && a.markRow == a.row && a.markCol == a.col {
+			a.mark = false
+		}
+	case ev.Mod&termbox.ModMotion != 0:
+		// Dragging extends the selection.
+		a.row, a.col = row, col
+	default:
+		a.row, a.col = row, col
+		a.mark = true
+		a.markRow, a.markCol = row, col
+	}
+	a.goal = -1
+	a.lastEdit = editNone
+	a.fixView()
+	a.mu.Unlock()
+
+	QueueRedraw(a)
+}
+
+func (a *TextAreaType) key(ev *termbox.Event) *Event {
+
+	a.mu.Lock()
+	_, h := a.Size()
+	before := a.text()
+	goal := -1
+
+	switch ev.Key {
+	case termbox.KeyArrowLeft:
+		if a.col > 0 {
+			a.col--
+		} else if a.row > 0 {
+			a.row--
+			a.col = len(a.lines[a.row])
+		}
+	case termbox.KeyArrowRight:
+		if a.col < len(a.lines[a.row]) {
+			a.col++
+		} else if a.row+1 < len(a.lines) {
+			a.row++
+			a.col = 0
+		}
+	case termbox.KeyArrowUp:
+		a.moveRows(-1)
+		goal = a.goal
+	case termbox.KeyArrowDown:
+		a.moveRows(1)
+		goal = a.goal
+	case termbox.KeyPgup:
+		a.moveRows(-h)
+		goal = a.goal
+	case termbox.KeyPgdn:
+		a.moveRows(h)
+		goal = a.goal
+	case termbox.KeyHome:
+		a.col = 0
+	case termbox.KeyEnd:
+		a.col = len(a.lines[a.row])
+	case termbox.KeyEsc:
+		a.mark = false
+	case termbox.KeyCtrlC:
+		a.clipboard = a.selected()
+	case termbox.KeyCtrlX:
+		if _, _, _, _, ok := a.selection(); ok {
+			a.snapshot(editOther)
+			a.clipboard = a.selected()
+			a.deleteSelection()
+		}
+	case termbox.KeyCtrlV:
+		if a.clipboard != "" {
+			a.snapshot(editOther)
+			a.insert(a.clipboard)
+		}
+	case termbox.KeyCtrlZ:
+		a.restore(&a.undo, &a.redo)
+	case termbox.KeyCtrlY:
+		a.restore(&a.redo, &a.undo)
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		_, _, _, _, sel := a.selection()
+		if !sel && a.col == 0 && a.row == 0 {
+			// Nothing to delete.
+			break
+		}
+		a.snapshot(editDelete)
+		if a.deleteSelection() {
+			break
+		}
+		if a.col > 0 {
+			line := a.lines[a.row]
+			a.lines[a.row] = append(line[:a.col-1], line[a.col:]...)
+			a.col--
+		} else if a.row > 0 {
+			a.col = len(a.lines[a.row-1])
+			a.lines[a.row-1] = append(a.lines[a.row-1], a.lines[a.row]...)
+			a.lines = append(a.lines[:a.row], a.lines[a.row+1:]...)
+			a.row--
+		}
+	case termbox.KeyDelete:
+		_, _, _, _, sel := a.selection()
+		if !sel && a.col == len(a.lines[a.row]) && a.row+1 == len(a.lines) {
+			break
+		}
+		a.snapshot(editDelete)
+		if a.deleteSelection() {
+			break
+		}
+		line := a.lines[a.row]
+		if a.col < len(line) {
+			a.lines[a.row] = append(line[:a.col], line[a.col+1:]...)
+		} else if a.row+1 < len(a.lines) {
+			a.lines[a.row] = append(line, a.lines[a.row+1]...)
+			a.lines = append(a.lines[:a.row+1], a.lines[a.row+2:]...)
+		}
+	case termbox.KeyEnter:
+		a.snapshot(editOther)
+		a.insert("\n")
+	case termbox.KeyTab:
+		a.snapshot(editInsert)
+		n := 1
+		if a.TabWidth > 0 {
+			x := runewidth.StringWidth(string(a.lines[a.row][:a.col]))
+			n = a.TabWidth - x%a.TabWidth
+		}
+		a.insert(strings.Repeat(" ", n))
+	case termbox.KeySpace:
+		a.snapshot(editInsert)
+		a.insert(" ")
+	default:
+		if ev.Key == termbox.KeyCtrlSpace && ev.Ch == 0 {
+			a.mark = true
+			a.markRow, a.markCol = a.row, a.col
+		} else if ev.Ch != 0 && ev.Key == 0 {
+			a.snapshot(editInsert)
+			a.insert(string(ev.Ch))
+		}
+	}
+
+	a.goal = goal
+	text := a.text()
+	if text == before {
+		a.lastEdit = editNone
+	}
+	a.fixView()
+	a.mu.Unlock()
+
+	QueueRedraw(a)
+	if text == before {
+		return WidgetResult(Nop)
+	}
+	return NewEvent(WindEventTextChange, text)
+}
+
+func (a *TextAreaType) Refresh() {
+
+	if !a.Managed() {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	w, h := a.Size()
+	fg, bg := a.Colors()
+	rows := a.rows()
+	sr, sc, er, ec, sel := a.selection()
+
+	for y := 0; y < h; y++ {
+		i := a.top + y
+		if i >= len(rows) {
+			drawLine(a, y, "", AlignLeft, fg, bg)
+			continue
+		}
+		r := rows[i]
+		line := a.lines[r.line]
+		x := 0
+		if a.Wrap == WrapNone {
+			x = -a.left
+		}
+		for c := r.start; c < r.end && x < w; c++ {
+			rw := runewidth.RuneWidth(line[c])
+			f, b := fg, bg
+			if sel && (r.line > sr || (r.line == sr && c >= sc)) &&
+				(r.line < er || (r.line == er && c < ec)) {
+				f |= AttrReverse
+			}
+			switch {
+			case x >= 0 && x+rw <= w:
+				SetCell(a, x, y, line[c], f, b)
+			case x+rw > 0:
+				// Partly in view.
+				for i := 0; i < rw; i++ {
+					if x+i >= 0 && x+i < w {
+						SetCell(a, x+i, y, ' ', f, b)
+					}
+				}
+			}
+			x += rw
+		}
+		if x < 0 {
+			x = 0
+		}
+		for ; x < w; x++ {
+			SetCell(a, x, y, ' ', fg, bg)
+		}
+	}
+
+	if a.focused {
+		i := a.cursorRow(rows)
+		x := a.cursorCell(rows[i])
+		if a.Wrap == WrapNone {
+			x -= a.left
+		}
+		if x >= w {
+			x = w - 1
+		}
+		SetCursor(a, x, i-a.top)
+		return
+	}
+	releaseCursor(a)
+}
@@ -0,0 +1,325 @@
+package windigo
+
+import (
+	"sync"
+	"unicode"
+
+	runewidth "github.com/mattn/go-runewidth"
+	termbox "github.com/nsf/termbox-go"
+)
+
+// TextInputType is a single line text entry widget.  It takes the
+// keyboard focus when clicked (or when sent WindEventFocus) and shows the
+// terminal cursor while it has it.  Text wider than the widget scrolls
+// horizontally to keep the cursor in view.
+//
+// Keys:
+//
+//	Left, Right, Home (Ctrl-A), End (Ctrl-E)   move the cursor
+//	Backspace, Delete                          delete a rune
+//	Ctrl-K, Ctrl-U                             delete to the end, start
+//	Ctrl-W                                     delete the previous word
+//	Insert                                     toggle overwrite
+//	Up, Down                                   browse the input history
+//	Enter                                      submit
+//
+// Every edit sends WindEventTextChange to the container and Enter sends
+// WindEventSubmit, both with the text.  Submitted text is added to the
+// widget's history.
+type TextInputType struct {
+	WidgetType
+
+	// Clear the text after it is submitted.
+	ClearOnSubmit bool
+	// How many submitted lines to remember, 0 means no limit.
+	HistoryLen int
+
+	mu        sync.Mutex
+	text      []rune
+	cursor    int
+	scroll    int
+	overwrite bool
+	focused   bool
+	history   []string
+	// Position in history while browsing it, len(history) when not,
+	// and the line being edited before browsing started.
+	histPos int
+	draft   string
+}
+
+func NewTextInput(r *Region, fg, bg Attribute) (*TextInputType, error) {
+
+	t := new(TextInputType)
+	t.X = r.X
+	t.Y = r.Y
+	t.W = r.W
+	t.H = r.H
+	t.Fg = fg
+	t.Bg = bg
+	t.kbd = -1
+	t.allowFocus = true
+	t.Fsm = NewEventFSM(t.event)
+	return t, nil
+}
+
+func (t *TextInputType) Init() error {
+
+	x, y := t.Loc()
+	w, h := t.Size()
+	p := t.Ancestor()
+
+	r := Region{TopLeft{x, y}, WidthHeight{w, h}, false, false, false}
+	c, err := RegClickable(p, r)
+	if err != nil {
+		return err
+	}
+	t.InputChan = append(t.InputChan, c)
+
+	t.Start()
+
+	return nil
+}
+
+// Text returns the text being edited.
+func (t *TextInputType) Text() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.text)
+}
+
+// SetText replaces the text and moves the cursor to its end.
+func (t *TextInputType) SetText(s string) {
+	t.mu.Lock()
+	t.setText(s)
+	t.mu.Unlock()
+
+	if t.Managed() {
+		QueueRedraw(t)
+	}
+}
+
+// History returns the submitted lines, oldest first.
+func (t *TextInputType) History() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]string(nil), t.history...)
+}
+
+// SetHistory replaces the input history, oldest first.
+func (t *TextInputType) SetHistory(h []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.history = append([]string(nil), h...)
+	t.histPos = len(t.history)
+}
+
+func (t *TextInputType) setText(s string) {
+	t.text = []rune(s)
+	t.cursor = len(t.text)
+	t.fixScroll()
+}
+
+// fixScroll keeps the cursor within the widget.  t.mu is held.
+func (t *TextInputType) fixScroll() {
+	w, _ := t.Size()
+	if t.cursor < t.scroll {
+		t.scroll = t.cursor
+	}
+	for t.scroll < t.cursor &&
+		runewidth.StringWidth(string(t.text[t.scroll:t.cursor])) >= w {
+		t.scroll++
+	}
+}
+
+func (t *TextInputType) event(e *Event) *Event {
+
+	switch e.EventType {
+	case WindEventInput:
+		ev := e.Args.Tbox
+		switch ev.Type {
+		case termbox.EventKey:
+			return t.key(ev)
+		case termbox.EventMouse:
+			if ev.Key == termbox.MouseLeft {
+				t.click(ev.MouseX)
+			}
+		}
+	case WindEventFocus:
+		on, _ := e.Payload.(bool)
+		t.mu.Lock()
+		t.focused = on
+		t.mu.Unlock()
+		QueueRedraw(t)
+	case WindEventSetText:
+		s, _ := e.Payload.(string)
+		t.SetText(s)
+	}
+	return WidgetResult(Nop)
+}
+
+// click takes the focus and moves the cursor to column x.
+func (t *TextInputType) click(x int) {
+	t.ReqFocus()
+
+	t.mu.Lock()
+	t.focused = t.HaveFocus()
+	col := 0
+	t.cursor = len(t.text)
+	for i := t.scroll; i < len(t.text); i++ {
+		col += runewidth.RuneWidth(t.text[i])
+		if col > x {
+			t.cursor = i
+			break
+		}
+	}
+	t.fixScroll()
+	t.mu.Unlock()
+
+	QueueRedraw(t)
+}
+
+func (t *TextInputType) key(ev *termbox.Event) *Event {
+
+	t.mu.Lock()
+	before := string(t.text)
+	submit := false
+
+	switch ev.Key {
+	case termbox.KeyArrowLeft:
+		if t.cursor > 0 {
+			t.cursor--
+		}
+	case termbox.KeyArrowRight:
+		if t.cursor < len(t.text) {
+			t.cursor++
+		}
+	case termbox.KeyHome, termbox.KeyCtrlA:
+		t.cursor = 0
+	case termbox.KeyEnd, termbox.KeyCtrlE:
+		t.cursor = len(t.text)
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if t.cursor > 0 {
+			t.text = append(t.text[:t.cursor-1], t.text[t.cursor:]...)
+			t.cursor--
+		}
+	case termbox.KeyDelete:
+		if t.cursor < len(t.text) {
+			t.text = append(t.text[:t.cursor], t.text[t.cursor+1:]...)
+		}
+	case termbox.KeyCtrlK:
+		t.text = t.text[:t.cursor]
+	case termbox.KeyCtrlU:
+		t.text = append([]rune(nil), t.text[t.cursor:]...)
+		t.cursor = 0
+	case termbox.KeyCtrlW:
+		i := t.cursor
+		for i > 0 && unicode.IsSpace(t.text[i-1]) {
+			i--
+		}
+		for i > 0 && !unicode.IsSpace(t.text[i-1]) {
+			i--
+		}
+		t.text = append(t.text[:i], t.text[t.cursor:]...)
+		t.cursor = i
+	case termbox.KeyInsert:
+		t.overwrite = !t.overwrite
+	case termbox.KeyArrowUp:
+		if t.histPos > 0 {
+			if t.histPos == len(t.history) {
+				t.draft = string(t.text)
+			}
+			t.histPos--
+			t.setText(t.history[t.histPos])
+		}
+	case termbox.KeyArrowDown:
+		if t.histPos < len(t.history) {
+			t.histPos++
+			if t.histPos == len(t.history) {
+				t.setText(t.draft)
+			} else {
+				t.setText(t.history[t.histPos])
+			}
+		}
+	case termbox.KeyEnter:
+		submit = true
+	case termbox.KeySpace:
+		t.insert(' ')
+	default:
+		if ev.Ch != 0 && ev.Key == 0 {
+			t.insert(ev.Ch)
+		}
+	}
+
+	var out *Event
+	text := string(t.text)
+	if submit {
+		if text != "" && (len(t.history) == 0 || t.history[len(t.history)-1] != text) {
+			t.history = append(t.history, text)
+			if t.HistoryLen > 0 && len(t.history) > t.HistoryLen {
+				t.history = t.history[len(t.history)-t.HistoryLen:]
+			}
+		}
+		t.histPos = len(t.history)
+		t.draft = ""
+		if t.ClearOnSubmit {
+			t.setText("")
+		}
+		out = NewEvent(WindEventSubmit, text)
+	} else if text != before {
+		out = NewEvent(WindEventTextChange, text)
+	}
+	t.fixScroll()
+	t.mu.Unlock()
+
+	QueueRedraw(t)
+	if out == nil {
+		return WidgetResult(Nop)
+	}
+	return out
+}
+
+// insert puts r at the cursor, or over the rune at the cursor in
+// overwrite mode.  t.mu is held.
+func (t *TextInputType) insert(r rune) {
+	if t.overwrite && t.cursor < len(t.text) {
+		t.text[t.cursor] = r
+	} else {
+		t.text = append(t.text, 0)
+		copy(t.text[t.cursor+1:], t.text[t.cursor:])
+		t.text[t.cursor] = r
+	}
+	t.cursor++
+}
+
+func (t *TextInputType) Refresh() {
+
+	if !t.Managed() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, h := t.Size()
+	fg, bg := t.Colors()
+
+	x := drawText(t, 0, 0, w, string(t.text[t.scroll:]), fg, bg)
+	for ; x < w; x++ {
+		SetCell(t, x, 0, ' ', fg, bg)
+	}
+	for y := 1; y < h; y++ {
+		drawLine(t, y, "", AlignLeft, fg, bg)
+	}
+
+	if t.focused {
+		col := runewidth.StringWidth(string(t.text[t.scroll:t.cursor]))
+		if col < w {
+			SetCursor(t, col, 0)
+			return
+		}
+	}
+	releaseCursor(t)
+}
@@ -0,0 +1,29 @@
+package windigo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFocusMoves(t *testing.T) {
+	a, _ := NewTextInput(NewRegion(0, 0, 10, 1), 0, 0)
+	b, _ := NewTextInput(NewRegion(0, 1, 10, 1), 0, 0)
+	a.ReqFocus()
+
+	got := make(chan *Event, 1)
+	go func() { got <- a.Poll() }()
+	b.ReqFocus()
+
+	// a hears at once, not on the next key press.
+	select {
+	case e := <-got:
+		if on, _ := e.Payload.(bool); e.EventType != WindEventFocus || on {
+			t.Fatalf("got %s %v, want Focus false", e.EventType, e.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the old owner was not told it lost the focus")
+	}
+	if a.HaveFocus() || !b.HaveFocus() {
+		t.Fatalf("a has focus %v, b %v", a.HaveFocus(), b.HaveFocus())
+	}
+}
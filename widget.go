@@ -3,6 +3,7 @@ package windigo
 import (
 	"errors"
 	"reflect"
+	"unicode"
 	"unsafe"
 
 	termbox "github.com/nsf/termbox-go"
@@ -41,6 +42,8 @@ type WidgetType struct {
 	// kbd is an index into inputchan. kbd == -1 if no keyboard/focus.
 	kbd       int
 	InputChan []chan *termbox.Event
+	// Signalled by the Screen when another widget takes the focus.
+	blur chan struct{}
 
 	Comm []IChing
 
@@ -63,6 +66,8 @@ type WidgetType struct {
 	// or "popup" geographically close to the widget that produced it.
 	oneshot bool
 	managed bool
+	// Set once Start has run the InputEventMgr.
+	started bool
 
 	// Our container.
 	Parent Container
@@ -150,6 +155,7 @@ func (w *WidgetType) SetManaged() {
 }
 
 func (w *WidgetType) Init() error {
+	w.Start()
 	return nil
 }
 
@@ -162,27 +168,180 @@ func (w *WidgetType) GetComm() []IChing {
 }
 
 func (w *WidgetType) PushEvent(e *Event) {
+	publish(w.Comm[0].Yang, e)
 	w.Comm[0].Yang <- e
 }
 
+// PollEvent waits for the next input event on any of the widget's
+// input channels.  It returns nil when the widget's container sends
+// WindEventExit.  Input channels that are closed, i.e. the keyboard
+// channel when another widget takes focus, are dropped.  Timed
+// transitions of the widget's state machine are taken while it waits;
+// they run the new state's entry actions, not its function.
+// Other windigo events from the container are discarded, use Poll to
+// receive them.
 func (w *WidgetType) PollEvent() *termbox.Event {
+	for {
+		e := w.Poll()
+		if e == nil {
+			return nil
+		}
+		if e.EventType == WindEventInput {
+			return e.Args.Tbox
+		}
+	}
+}
 
-	channels := w.InputChan
-	n := len(channels)
-
-	var selectCase = make([]reflect.SelectCase, n)
+// Poll is PollEvent for both sources: it returns the next termbox input
+// event, wrapped by InputEvent, or the next windigo event sent by the
+// widget's container on Comm[0].Yin.  WindEventExit, WindEventQuery and
+// WindEventSetState are handled here and are not returned.  A
+// WindEventFocus from the container asks for the keyboard focus; it is
+// returned with the widget's resulting focus, and one is also returned
+// when the widget loses focus.
+func (w *WidgetType) Poll() *Event {
 
-	for i := 0; i < n; i++ {
-		selectCase[i].Dir = reflect.SelectRecv
-		selectCase[i].Chan = reflect.ValueOf(channels[i])
+	var yin chan *Event
+	if len(w.Comm) > 0 {
+		yin = w.Comm[0].Yin
 	}
+	var timer <-chan struct{}
+	if w.Fsm != nil {
+		timer = w.Fsm.TimerC()
+	}
+
+	for {
+		channels := w.InputChan
+		n := len(channels)
+
+		var selectCase = make([]reflect.SelectCase, n+3)
 
-	_, recv, recvOk := reflect.Select(selectCase)
-	if recvOk {
+		for i := 0; i < n; i++ {
+			selectCase[i].Dir = reflect.SelectRecv
+			selectCase[i].Chan = reflect.ValueOf(channels[i])
+		}
+		selectCase[n].Dir = reflect.SelectRecv
+		selectCase[n].Chan = reflect.ValueOf(yin)
+		selectCase[n+1].Dir = reflect.SelectRecv
+		selectCase[n+1].Chan = reflect.ValueOf(timer)
+		selectCase[n+2].Dir = reflect.SelectRecv
+		selectCase[n+2].Chan = reflect.ValueOf(w.blur)
+
+		chosen, recv, recvOk := reflect.Select(selectCase)
+		if chosen == n+2 {
+			// Another widget has taken the focus.  The stale
+			// keyboard channel is kept until the router closes
+			// it, in case a key is already on its way.
+			if w.haveFocus && (w.kbd < 0 || w.kbd >= n ||
+				!screen.focused(channels[w.kbd])) {
+				w.haveFocus = false
+				return NewEvent(WindEventFocus, false)
+			}
+			continue
+		}
+		if chosen == n+1 {
+			// Taking the transition runs the entry actions and
+			// redraws; the new state's function waits for input.
+			w.Fsm.Expire()
+			continue
+		}
+		if chosen == n {
+			if !recvOk {
+				return nil
+			}
+			wev := (*Event)(unsafe.Pointer(recv.Pointer()))
+			if wev == nil {
+				continue
+			}
+			switch wev.EventType {
+			case WindEventExit, WindEventQuery, WindEventSetState:
+				if w.handle(wev) {
+					return nil
+				}
+				continue
+			case WindEventFocus:
+				if on, _ := wev.Payload.(bool); on && w.allowFocus {
+					w.ReqFocus()
+				}
+				return NewEvent(WindEventFocus, w.haveFocus)
+			}
+			return wev
+		}
+		if !recvOk {
+			lost := chosen == w.kbd && w.haveFocus
+			w.dropInput(chosen)
+			if lost {
+				return NewEvent(WindEventFocus, false)
+			}
+			continue
+		}
 		ev := *(*termbox.Event)(unsafe.Pointer(recv.Pointer()))
-		return &ev
+		return InputEvent(&ev)
+	}
+}
+
+// dropInput removes input channel i, giving up focus if it was the
+// keyboard channel.
+func (w *WidgetType) dropInput(i int) {
+	w.InputChan = append(w.InputChan[:i], w.InputChan[i+1:]...)
+	if i == w.kbd {
+		w.kbd = -1
+		w.haveFocus = false
+	} else if i < w.kbd {
+		w.kbd--
+	}
+}
+
+// handle deals with events from the widget's container.  It returns
+// true if the widget should exit.  A WindEventQuery is answered with
+// the widget's current state.
+func (w *WidgetType) handle(wev *Event) bool {
+	if wev == nil {
+		return false
+	}
+	switch wev.EventType {
+	case WindEventExit:
+		return true
+	case WindEventQuery:
+		if w.Fsm == nil {
+			Respond(w, wev, Fail, ErrNoReply)
+		} else {
+			Respond(w, wev, Ok, int(w.Fsm.State()))
+		}
+	case WindEventSetState:
+		if w.Fsm == nil {
+			Respond(w, wev, Fail, ErrNoFSM)
+			break
+		}
+		name, _ := wev.Payload.(string)
+		if err := w.Fsm.Restore(name); err != nil {
+			Respond(w, wev, Fail, err)
+		} else {
+			Respond(w, wev, Ok, int(w.Fsm.State()))
+		}
+	}
+	return false
+}
+
+// idle waits for the container to send WindEventExit.
+func (w *WidgetType) idle() {
+	if len(w.Comm) == 0 {
+		return
+	}
+	for wev := range w.Comm[0].Yin {
+		if w.handle(wev) {
+			return
+		}
+	}
+}
+
+// done tells the widget's container that the widget's InputEventMgr
+// has returned.
+func (w *WidgetType) done() {
+	p := w.Ancestor()
+	if p != nil {
+		p.Done()
 	}
-	return nil
 }
 
 func (w *WidgetType) Clear() {
@@ -204,10 +363,20 @@ func (w *WidgetType) ReqFocus() (chan *termbox.Event, error) {
 			err := errors.New("kbd input channel error")
 			return nil, err
 		}
-		return channels[kbd], nil
+		if screen.focused(channels[kbd]) {
+			return channels[kbd], nil
+		}
+		// Another widget has taken the focus since, and the router
+		// has yet to close this channel.  Drop it and ask again.
+		w.dropInput(kbd)
+		havefocus = false
+		n = len(w.InputChan)
 	}
 	if !havefocus && allowfocus {
-		channel, _ = screen.RequestFocus()
+		if w.blur == nil {
+			w.blur = make(chan struct{}, 1)
+		}
+		channel, _ = screen.requestFocus(w)
 		if channel != nil {
 			w.InputChan = append(w.InputChan, channel)
 			w.kbd = n
@@ -223,16 +392,20 @@ func (w *WidgetType) AddInput(c chan *termbox.Event) {
 
 func (w *WidgetType) InputEventMgr() {
 
-	//channels := w.InputChannels()
-	channels := w.InputChan
-	n := len(channels)
+	defer w.done()
+
 	fsm := w.Fsm
-	if n == 0 || fsm == nil {
+	if fsm == nil {
+		w.idle()
 		return
 	}
+	defer fsm.Stop()
 
 	ev := new(termbox.Event)
 	ev.Type = termbox.EventNone
+	e := InputEvent(ev)
+
+	fsm.Begin()
 
 	// Upon initialization, state should be ENTRY (0), and the
 	// widget initialization should take place in the statefunc[ENTRY]
@@ -241,35 +414,42 @@ func (w *WidgetType) InputEventMgr() {
 	// an entry like {ENTRY, Ok, ACTIVE} where ACTIVE is the first/
 	// default(possibly only) active state.
 	// Entering the EXIT state will cause the eventmgr for that
-	// widget to exit.
+	// widget to stop running the state machine.  It then idles until
+	// its container sends WindEventExit.
 loop:
 	for {
 		// State functions test termbox input events like
-		// ev.Key == termbox.MouseLeft and coordinates
-		// and return Ok, Fail, or Repeat.
+		// ev.Key == termbox.MouseLeft and coordinates, or windigo
+		// events sent by the container, and return Ok, Fail, or Repeat.
 		// The FSM for a widget should be initialized by the
 		// function that creates the widget, i.e. NewScrollBar().
-		Func := fsm.StateFunc[fsm.CurrentState]
-		wev := Func(ev)
-		// widgets will send event to container whenever
-		// the state function returns Ok or Repeat.
-		if wev.Result.Rc == Fail {
-			e := WidgetResult(Fail, "Widget state machine returned Fail")
-			err := errors.New("InputEventMgr: fsm returns Fail")
-			_ = err
-			w.PushEvent(e)
-		}
-		if wev.Result.Rc != Fail && wev.Result.Rc != Nop {
-			w.PushEvent(wev)
+		// States without a function for the event ignore it.
+		wev := fsm.Call(e)
+		if wev != nil {
+			// widgets will send event to container whenever
+			// the state function returns Ok or Repeat.
+			if wev.Result.Rc == Fail {
+				e := WidgetResult(Fail, "Widget state machine returned Fail")
+				err := errors.New("InputEventMgr: fsm returns Fail")
+				_ = err
+				w.PushEvent(e)
+			}
+			if wev.Result.Rc != Fail && wev.Result.Rc != Nop {
+				w.PushEvent(wev)
+			}
+			if fsm.CurrentState == fsm.ExitState {
+				err := errors.New("exiting FSM")
+				_ = err
+				break loop
+			}
+			fsm.Fire(e, wev)
 		}
-		if fsm.CurrentState == fsm.ExitState {
-			err := errors.New("exiting FSM")
-			_ = err
-			break loop
+		e = w.Poll()
+		if e == nil {
+			return
 		}
-		fsm.CurrentState, _ = fsm.NextState(wev.Result.Rc)
-		ev = w.PollEvent()
 	}
+	w.idle()
 }
 
 /*
@@ -300,10 +480,19 @@ func (w *WidgetType) SetFSM(fsm *FiniteStateMachine) {
 	w.Fsm = fsm
 }
 
+func (w *WidgetType) FSM() *FiniteStateMachine {
+	return w.Fsm
+}
+
+// Start runs the widget's InputEventMgr.  Calling it again, i.e. from a
+// widget's own Init as well as WidgetType's, does nothing.
 func (w *WidgetType) Start() {
+	if w.started {
+		return
+	}
+	w.started = true
 	//go w.EventMgr()
-	go w.InputEventMgr()
-	Flush()
+	spawn(w.InputEventMgr)
 }
 
 /*
@@ -322,15 +511,33 @@ func (w *WidgetType) Press(ms time.Duration) {
 func String2Sigil(s string, fg, bg Attribute) *Sigil {
 
 	sigil := new(Sigil)
-	runes := []rune(s)
 
-	for _, r := range runes {
+	// A cell holds one rune, so a combining mark is folded into the
+	// rune before it, as drawText does, or dropped.
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			if n := len(*sigil); n > 0 {
+				if c, ok := compose((*sigil)[n-1].Ch, r); ok {
+					(*sigil)[n-1].Ch = c
+				}
+			}
+			continue
+		}
 		*sigil = append(*sigil, Cell{Ch: r, Fg: fg, Bg: bg})
 	}
 
 	return sigil
 }
 
+// String returns the characters of the Sigil.
+func (s Sigil) String() string {
+	runes := make([]rune, len(s))
+	for i, c := range s {
+		runes[i] = c.Ch
+	}
+	return string(runes)
+}
+
 func (widget *WidgetType) Refresh() {
 
 	if !widget.Managed() {
@@ -342,12 +549,14 @@ func (widget *WidgetType) Refresh() {
 
 	fsm := widget.Fsm
 	if fsm != nil {
-		state := fsm.CurrentState
-		s := fsm.Sigil[state]
+		s := fsm.Glyph()
 
 		for i := 0; i < w; i++ {
 			for j := 0; j < h; j++ {
-				SetCell(widget, i, j, s[i*j].Ch, s[i*j].Fg, s[i*j].Bg)
+				c := s.At(i, j)
+				if c.Ch != 0 {
+					SetCell(widget, i, j, c.Ch, c.Fg, c.Bg)
+				}
 			}
 		}
 	}
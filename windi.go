@@ -1,9 +1,12 @@
 package windigo
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
+	"time"
 	"unsafe"
 
 	runewidth "github.com/mattn/go-runewidth"
@@ -171,6 +174,54 @@ func SetCell(w Object, x, y int, r rune, fg, bg Attribute) error {
 	return err
 }
 
+// The Object that last showed the terminal cursor, used only on the
+// render goroutine.
+var cursorOwner Object
+
+// SetCursor shows the terminal cursor at x, y of o.  Like SetCell it
+// should only be called on the render goroutine, normally from o's
+// Refresh.
+func SetCursor(o Object, x, y int) error {
+	err := setCursor(o, x, y)
+	if err == nil {
+		cursorOwner = o
+	}
+	return err
+}
+
+func setCursor(o Object, x, y int) error {
+	if !o.Managed() {
+		return errors.New("SetCursor: unmanaged object")
+	}
+	p := o.Ancestor()
+	if p == nil {
+		termbox.SetCursor(x, y)
+		return nil
+	}
+	w, h := o.Size()
+	if x < 0 || x >= w || y < 0 || y >= h {
+		return fmt.Errorf("SetCursor: %d, %d out of range: %d %d", x, y, w, h)
+	}
+	dx, dy := o.Loc()
+	return setCursor(p, x+dx, y+dy)
+}
+
+// HideCursor hides the terminal cursor.
+func HideCursor() {
+	termbox.HideCursor()
+	cursorOwner = nil
+}
+
+// releaseCursor hides the terminal cursor if o showed it.  A widget that
+// shows the cursor while it has the focus calls it from Refresh when it
+// hasn't, so the cursor is not left behind when the focus moves to a
+// widget without one.
+func releaseCursor(o Object) {
+	if cursorOwner == o {
+		HideCursor()
+	}
+}
+
 func RegClickable(w Object, r Region) (chan *termbox.Event, error) {
 	if w.Managed() {
 		p := w.Ancestor()
@@ -196,7 +247,9 @@ func RegClickable(w Object, r Region) (chan *termbox.Event, error) {
 func (w *WindowType) Main() {
 }
 
-func Init() *WindowType {
+// Init initializes termbox and returns the root window.  The UI lives
+// until ctx is cancelled, see Run.
+func Init(ctx context.Context) *WindowType {
 
 	err := termbox.Init()
 
@@ -204,6 +257,8 @@ func Init() *WindowType {
 		panic(err)
 	}
 
+	screen.ctx = ctx
+
 	termbox.SetInputMode(termbox.InputEsc | termbox.InputMouse)
 	termbox.SetOutputMode(termbox.Output256)
 	ScreenSizeX, ScreenSizeY = termbox.Size()
@@ -212,6 +267,8 @@ func Init() *WindowType {
 
 	OutlineChars = make([]rune, len(defOutlineChars))
 	copy(OutlineChars, defOutlineChars[:])
+	SliderChars = make([]rune, len(defSliderChars))
+	copy(SliderChars, defSliderChars[:])
 
 	r0 := new(Region)
 	r0.X = 0
@@ -238,17 +295,73 @@ func Init() *WindowType {
 
 	win.addEdges2Lines()
 
-	screen.windows = append(screen.windows, *win)
+	screen.windows = append(screen.windows, win)
 
-	go screen.InputEventRouter()
+	spawn(screen.InputEventRouter)
+	spawn(render.loop)
+	win.Init()
 
 	return win
 }
 
+// Run blocks until the context given to Init is cancelled.  It then
+// sends WindEventExit to the root window, which propagates it down the
+// object tree, waits for every EventMgr and InputEventMgr to return and
+// restores the terminal with Close.
+func Run() {
+	<-screen.ctx.Done()
+
+	exit := NewEvent(WindEventExit)
+loop:
+	for {
+		select {
+		case screen.Comm.Yang <- exit:
+			break loop
+		case <-screen.Comm.Yin:
+			// Discard root window output while shutting down.
+		}
+	}
+	termbox.Interrupt()
+
+	screen.wg.Wait()
+	Close()
+}
+
+// spawn runs f in a goroutine that Run waits for.
+func spawn(f func()) {
+	screen.wg.Add(1)
+	go func() {
+		defer screen.wg.Done()
+		f()
+	}()
+}
+
+// How long a container waits for its children to accept WindEventExit,
+// and then for them to return.  Objects without an event manager would
+// otherwise hang shutdown.
+var exitTimeout = time.Second
+
+// waitTimeout waits for wg, giving up after d.
+func waitTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+	c := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(c)
+	}()
+	select {
+	case <-c:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
 func Close() {
 	termbox.Close()
 }
 
+// Flush draws the changes made with SetCell to the terminal.  It should
+// only be called on the render goroutine, see QueueUpdate.
 func Flush() {
 	termbox.Flush()
 }
@@ -371,6 +484,7 @@ func AddComm(c Container, w Object) {
 	comm.Yang = make(chan *Event)
 
 	c.AddComm(*comm)
+	registry.setSource(comm.Yin, w)
 
 	t := comm.Yin
 	comm.Yin = comm.Yang
@@ -379,24 +493,92 @@ func AddComm(c Container, w Object) {
 	w.AddComm(*comm)
 }
 
+// EventMgr receives events from a container's parent (comm[0]) and
+// children (comm[n], n > 0) and dispatches them to the container's
+// EventHandlers.  It returns when the parent sends WindEventExit, after
+// passing WindEventExit on to the children.  Objects managed while
+// EventMgr is running are picked up via the container's wakeup channel.
 func EventMgr(o Object) {
 	comm := o.GetComm()
-	var selectCase = make([]reflect.SelectCase, len(comm))
-
-	for i, _ := range comm {
-		selectCase[i].Dir = reflect.SelectRecv
-		selectCase[i].Chan = reflect.ValueOf(comm[i].Yin)
+	if len(comm) == 0 {
+		return
 	}
+	wake := registry.wakeup(comm[0].Yin)
 
 	for {
+		// Events a Request took from the children while waiting.
+		for _, h := range registry.release(comm[0].Yin) {
+			dispatch(o, h.from, h.e)
+		}
+
+		comm = o.GetComm()
+		n := len(comm)
+		var selectCase = make([]reflect.SelectCase, n+1)
+
+		for i := range comm {
+			selectCase[i].Dir = reflect.SelectRecv
+			selectCase[i].Chan = reflect.ValueOf(comm[i].Yin)
+		}
+		selectCase[n].Dir = reflect.SelectRecv
+		selectCase[n].Chan = reflect.ValueOf(wake)
+
 		chosen, recv, recvOk := reflect.Select(selectCase)
-		if recvOk {
-			// use recv.Pointer() which is uintptr cast of *Event.
-			wev := *(*Event)(unsafe.Pointer(recv.Pointer()))
-			// use EventType and chosen to determine which
-			// child object produced the event.
-			_ = fmt.Sprintf("%T", wev, chosen)
+		if chosen == n || !recvOk {
+			continue
 		}
+		// use recv.Pointer() which is uintptr cast of *Event.
+		wev := (*Event)(unsafe.Pointer(recv.Pointer()))
+		if wev == nil {
+			continue
+		}
+		if chosen == 0 && wev.EventType == WindEventExit {
+			exitChildren(comm)
+			for i := 1; i < len(comm); i++ {
+				unsubscribeAll(comm[i].Yin)
+			}
+			registry.forget(comm)
+			return
+		}
+		if chosen > 0 && deliver(wev) {
+			continue
+		}
+		// use EventType and chosen to determine which
+		// child object produced the event.
+		dispatch(o, registry.source(comm[chosen].Yin), wev)
+	}
+}
+
+// exitChildren sends WindEventExit to each child, discarding anything
+// the child sends while we wait for it to accept.  Children that have
+// not accepted within exitTimeout, all told, are given up on.
+func exitChildren(comm []IChing) {
+	exit := NewEvent(WindEventExit)
+	deadline := time.Now().Add(exitTimeout)
+
+	for i := 1; i < len(comm); i++ {
+		timeout := time.After(time.Until(deadline))
+	loop:
+		for {
+			select {
+			case comm[i].Yang <- exit:
+				break loop
+			case <-comm[i].Yin:
+			case <-timeout:
+				break loop
+			}
+		}
+	}
+}
+
+// dispatch calls the EventHandlers the container o registered for the
+// event's type.
+func dispatch(o Object, from Object, e *Event) {
+	h, ok := o.(Handler)
+	if !ok {
+		return
+	}
+	for _, f := range h.Handlers(e.EventType) {
+		f(from, e)
 	}
 }
 
@@ -412,7 +594,7 @@ func Manage(c Container, w Object) error {
 	cw, ch := c.Size()
 	ww, wh := w.Size()
 	wx, wy := w.Loc()
-	fmt.Sprintf("%T %T %T %T %T %T", cw, ch, ww, wh, wx, wy)
+	_, _, _, _, _, _ = cw, ch, ww, wh, wx, wy
 
 	w.SetManaged()
 	w.SetAncestor(c)
@@ -420,12 +602,15 @@ func Manage(c Container, w Object) error {
 
 	c.AddChild(w)
 
-	// Call the managed object's Init() which should start the
-	// object's EventMgr.
-	// w.Init()
-	//
-	// Use Wait Group.
-	// Start managed object's eventmgr.
+	// Let the container's EventMgr know it has a new child.
+	if comm := c.GetComm(); len(comm) > 0 {
+		registry.kick(comm[0].Yin)
+	}
+
+	// The container's Manage method calls the managed object's Init()
+	// which should start the object's EventMgr, and counts it in the
+	// container's WaitGroup.  The object's EventMgr calls the
+	// container's Done() when it returns.
 	// A widget's eventmgr will also start it's InputEventMgr (if it
 	// has requested focus or registered clickables.
 	return nil
@@ -38,6 +38,9 @@ type WindowType struct {
 	Layout     LayoutType
 	RightMost  bool
 	BottomMost bool
+
+	// Application EventHandlers called by EventMgr.
+	handlerTable
 }
 
 func NewWindow(r *Region, fg, bg Attribute) *WindowType {
@@ -302,7 +305,7 @@ func (w *WindowType) AddLine(l1, l2 *Line, n int) (*Line, error) {
 
 // Make WindowType an Object.
 func (w *WindowType) Init() error {
-	go w.EventMgr()
+	spawn(w.EventMgr)
 	return nil
 }
 
@@ -449,13 +452,23 @@ func (w *WindowType) VertLines() []Line {
 
 // Make WindowType a Container.
 func (w *WindowType) Manage(o Object) error {
-	Manage(w, o)
+	err := Manage(w, o)
+	if err != nil {
+		return err
+	}
+	w.wg.Add(1)
 	o.Init()
+	QueueRedraw(o)
 	return nil
 }
 
 func (w *WindowType) EventMgr() {
 	EventMgr(w)
+	waitTimeout(&w.wg, exitTimeout)
+	p := w.Ancestor()
+	if p != nil {
+		p.Done()
+	}
 }
 
 func (w *WindowType) Done() {